@@ -0,0 +1,77 @@
+// usage-report 輸出食物辨識的用量與估算費用報表（依日期、模型、使用者彙總）。
+//
+//	go run ./cmd/usage-report -from 2026-01-01 -to 2026-01-31 -by day,model
+//
+// 資料庫連線沿用服務的 POSTGRES_* 環境變數（會先嘗試載入 .env）。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"project/models"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	from := flag.String("from", "", "起始日期 YYYY-MM-DD（台北時間，預設為結束日前 6 天）")
+	to := flag.String("to", "", "結束日期 YYYY-MM-DD（台北時間，包含當天，預設為今天）")
+	by := flag.String("by", "day,model,user", "彙總維度，逗號分隔：day / model / user")
+	flag.Parse()
+
+	_ = godotenv.Load()
+
+	start, end, err := models.UsageReportRange(*from, *to, time.Now())
+	if err != nil {
+		fail(err)
+	}
+	groups := make([]models.UsageGroupBy, 0)
+	for _, g := range strings.Split(*by, ",") {
+		groupBy := models.UsageGroupBy(strings.TrimSpace(g))
+		if !groupBy.Valid() {
+			fail(fmt.Errorf("不支援的彙總維度: %s", g))
+		}
+		groups = append(groups, groupBy)
+	}
+
+	db, err := models.PostgresNewWithError()
+	if err != nil {
+		fail(err)
+	}
+	defer db.Close()
+
+	fmt.Printf("\n辨識用量報表 %s ~ %s\n", start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+	for _, groupBy := range groups {
+		rows, err := db.RecognitionUsageReport(start, end, groupBy)
+		if err != nil {
+			fail(err)
+		}
+		printReport(groupBy, rows)
+	}
+}
+
+// printReport 以表格輸出一種彙總維度的報表，最後一列為合計
+func printReport(groupBy models.UsageGroupBy, rows []models.UsageSummary) {
+	fmt.Printf("\n[%s]\n", groupBy)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "key\tcalls\tcache_hits\tinput_tokens\toutput_tokens\tcost_usd\tavg_latency_ms\t")
+	for _, row := range append(rows, models.SumUsage(rows)) {
+		key := row.Key
+		if key == "" {
+			key = "(cache)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.4f\t%.0f\t\n",
+			key, row.Calls, row.CacheHits, row.InputTokens, row.OutputTokens, row.CostUSD, row.AvgLatencyMs)
+	}
+	w.Flush()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "usage-report:", err)
+	os.Exit(1)
+}
@@ -34,6 +34,16 @@ func (lc *LineController) Webhook(c *gin.Context) {
 		return
 	}
 
-	lc.lineService.HandleEvents(events)
+	if err := lc.lineService.HandleEvents(events); err != nil {
+		// 回傳非 2xx，讓 LINE 在開啟重送時稍後重送未被接受的事件
+		log.Printf("Webhook 事件未能全部排入佇列: %v", err)
+		response.New(c).Fail(503, "Service Unavailable").Send()
+		return
+	}
 	response.New(c).Success("OK").Send()
 }
+
+// Metrics 回傳 webhook 事件佇列的即時指標
+func (lc *LineController) Metrics(c *gin.Context) {
+	response.New(c).Success("OK").SetData(lc.lineService.QueueStats()).Send()
+}
@@ -1,72 +1,438 @@
 package controllers
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
 	"net/http"
+	"path"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"project/models"
+	"project/services/log"
 	response "project/services/responses"
-	"project/services/s3"
+	"project/services/storage"
 )
 
 var (
-	s3Controller   *S3Controller
+	s3Controller     *S3Controller
 	s3ControllerOnce sync.Once
 )
 
 func initS3Controller() {
-	uploader, err := s3.NewUploaderFromEnv()
+	st, err := storage.NewFromEnv()
 	if err != nil {
+		log.Error("儲存空間未設定 err=%s", err.Error())
 		return
 	}
-	s3Controller = NewS3Controller(uploader)
+	// 資料庫只用於刪除圖片時一併移除餐點紀錄，未設定時其餘 API 照常運作
+	db, err := models.SharedDB()
+	if err != nil {
+		log.Warn("圖片 API：連線資料庫失敗，刪除圖片時不會移除餐點紀錄 err=%s", err.Error())
+	}
+	s3Controller = NewS3Controller(st, db)
 }
 
-// S3Controller 處理 S3 相關 API
+// S3Controller 處理圖片儲存相關 API（儲存後端可為 S3 或本機檔案）
 type S3Controller struct {
-	uploader *s3.Uploader
+	storage storage.Storage
+	db      *models.DBManager
 }
 
-// NewS3Controller 建立 S3 控制器
-func NewS3Controller(uploader *s3.Uploader) *S3Controller {
-	return &S3Controller{uploader: uploader}
+// NewS3Controller 建立圖片儲存控制器；db 可為 nil（刪除圖片時不移除餐點紀錄）
+func NewS3Controller(st storage.Storage, db *models.DBManager) *S3Controller {
+	return &S3Controller{storage: st, db: db}
 }
 
-// S3GetImageHandler 供 route 註冊用：觸發時才從環境變數建立 S3，未設定則回 503
-func S3GetImageHandler(c *gin.Context) {
+// getS3Controller 觸發時才從環境變數建立儲存後端，未設定則回 503 並回傳 nil
+func getS3Controller(c *gin.Context) *S3Controller {
 	s3ControllerOnce.Do(initS3Controller)
 	if s3Controller == nil {
-		response.New(c).Fail(http.StatusServiceUnavailable, "S3 未設定").Send()
-		return
+		response.New(c).Fail(http.StatusServiceUnavailable, "儲存空間未設定").Send()
+	}
+	return s3Controller
+}
+
+// S3GetImageHandler 供 route 註冊用：取得圖片的簽章網址
+func S3GetImageHandler(c *gin.Context) {
+	if sc := getS3Controller(c); sc != nil {
+		sc.GetImage(c)
+	}
+}
+
+// S3ListImagesHandler 供 route 註冊用：分頁列出使用者的圖片
+func S3ListImagesHandler(c *gin.Context) {
+	if sc := getS3Controller(c); sc != nil {
+		sc.ListImages(c)
+	}
+}
+
+// S3DeleteImageHandler 供 route 註冊用：刪除使用者的圖片
+func S3DeleteImageHandler(c *gin.Context) {
+	if sc := getS3Controller(c); sc != nil {
+		sc.DeleteImage(c)
 	}
-	s3Controller.GetImage(c)
 }
 
-// GetImageReq 取得圖片 Presigned URL 的請求
+// LocalFileHandler 供 route 註冊用：驗證本機儲存的簽章網址並回傳檔案（儲存後端不是本機檔案時回 404）
+func LocalFileHandler(c *gin.Context) {
+	if sc := getS3Controller(c); sc != nil {
+		sc.LocalFile(c)
+	}
+}
+
+// GetImageReq 取得圖片簽章網址（S3 為 Presigned URL）的請求
 // 建議 DB 至少存 s3_key，查詢時帶入即可
 type GetImageReq struct {
-	// S3 物件完整 key，格式：food-images/{userID}/{timestamp}.jpg
-	// 上傳成功時 Upload() 回傳的值，存進 DB 後查詢用
+	// 物件完整 key，格式：food-images/{userID}/{內容的 SHA-256}{副檔名}
+	// 儲存圖片時寫入 meals.s3_key / meal_images.s3_key 的值
 	S3Key string `json:"s3_key" binding:"required"`
+	// 圖片版本：original（預設）、thumb（長邊 240px）、display（長邊 1080px），也可用 query ?variant= 指定
+	Variant string `json:"variant"`
 }
 
-// GetImage 回傳 S3 圖片的 Presigned URL
+// GetImage 回傳圖片（指定版本）的簽章網址；舊圖片沒有該版本時改回傳原圖，實際版本見回應的 variant
 // POST /s3/getImage
-// Body: {"s3_key": "food-images/U80b35e04529b5a8be1fc2b4545240e7d/20260218_111336.jpg"}
+// Body: {"s3_key": "food-images/U80b35e04529b5a8be1fc2b4545240e7d/7fbe3086e4c6aa8998c0d51c7c89622b00b712b7344393e38e38befe67acb456.jpg", "variant": "thumb"}
 func (sc *S3Controller) GetImage(c *gin.Context) {
 	var req GetImageReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.New(c).Fail(http.StatusBadRequest, "s3_key 必填").Send()
 		return
 	}
+	if req.Variant == "" {
+		req.Variant = c.Query("variant")
+	}
+	if !storage.ValidVariant(req.Variant) {
+		response.New(c).Fail(http.StatusBadRequest, "variant 需為 original、thumb 或 display").Send()
+		return
+	}
 
-	url, err := sc.uploader.PresignGetURL(c.Request.Context(), req.S3Key, 1*time.Hour)
+	ctx := c.Request.Context()
+	key, variant := req.S3Key, storage.VariantOriginal
+	if req.Variant != "" && req.Variant != storage.VariantOriginal {
+		variantKey := storage.VariantKey(req.S3Key, req.Variant)
+		if _, err := sc.storage.Head(ctx, variantKey); err == nil {
+			key, variant = variantKey, req.Variant
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			log.Error("取得圖片版本失敗 key=%s err=%s", variantKey, err.Error())
+		}
+	}
+
+	url, err := sc.storage.SignedURL(ctx, key, 1*time.Hour)
 	if err != nil {
 		response.New(c).Fail(http.StatusInternalServerError, "產生圖片連結失敗").Send()
 		return
 	}
 
-	response.New(c).Success("OK").SetData(gin.H{"url": url}).Send()
+	response.New(c).Success("OK").SetData(gin.H{"url": url, "variant": variant}).Send()
+}
+
+const (
+	defaultListImagesLimit = 20
+	maxListImagesLimit     = 100
+)
+
+// ImageItem 圖片列表的一筆（原圖）
+type ImageItem struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	URL          string    `json:"url"`
+	// 縮圖的簽章網址；舊圖片沒有縮圖時為空
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	// 最近一次儲存到餐點的時間（資料庫未設定時為空）
+	SavedAt *time.Time `json:"saved_at,omitempty"`
+}
+
+// ListImages 分頁列出使用者的原圖（不含縮圖等版本）與 1 小時有效的簽章網址
+// GET /s3/images?user_id=U80b35e04529b5a8be1fc2b4545240e7d&cursor=&limit=20
+// 使用者為 JWT 的 UserId claim；user_id 可省略，有帶時必須與 claim 相同。
+// 依儲存時間由新到舊排列（來自 meal_images）；資料庫未設定時改為直接列出儲存空間，依 key（內容的 SHA-256）排序。
+// limit 預設 20、最多 100，每頁最多 limit 張原圖，next_cursor 傳給下一頁的 cursor，空字串表示沒有下一頁
+func (sc *S3Controller) ListImages(c *gin.Context) {
+	userID, ok := imageOwner(c, c.Query("user_id"))
+	if !ok {
+		return
+	}
+	limit := defaultListImagesLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			response.New(c).Fail(http.StatusBadRequest, "limit 須為正整數").Send()
+			return
+		}
+		limit = min(n, maxListImagesLimit)
+	}
+
+	if sc.db != nil {
+		sc.listImagesByTime(c, userID, limit)
+		return
+	}
+	sc.listImagesByKey(c, userID, limit)
+}
+
+// listImagesByTime 依 meal_images 的儲存時間由新到舊分頁；已從儲存空間移除的照片會略過（該頁可能少於 limit 張）
+func (sc *S3Controller) listImagesByTime(c *gin.Context, userID string, limit int) {
+	var after *models.UserImage
+	if cursor := c.Query("cursor"); cursor != "" {
+		decoded, err := decodeImageCursor(cursor)
+		if err != nil {
+			response.New(c).Fail(http.StatusBadRequest, "cursor 格式錯誤").Send()
+			return
+		}
+		after = decoded
+	}
+	// 多取一筆判斷是否還有下一頁
+	images, err := sc.db.ListUserImages(userID, after, limit+1)
+	if err != nil {
+		log.Error("列出圖片失敗 userID=%s err=%s", userID, err.Error())
+		response.New(c).Fail(http.StatusInternalServerError, "列出圖片失敗").Send()
+		return
+	}
+	next := ""
+	if len(images) > limit {
+		images = images[:limit]
+		next = encodeImageCursor(images[limit-1])
+	}
+
+	ctx := c.Request.Context()
+	items := make([]ImageItem, 0, len(images))
+	for _, image := range images {
+		obj, err := sc.storage.Head(ctx, image.S3Key)
+		if err != nil {
+			if !errors.Is(err, storage.ErrNotFound) {
+				log.Error("取得圖片資訊失敗 key=%s err=%s", image.S3Key, err.Error())
+			}
+			continue
+		}
+		item, err := sc.imageItem(ctx, nil, *obj)
+		if err != nil {
+			log.Error("產生圖片連結失敗 key=%s err=%s", obj.Key, err.Error())
+			response.New(c).Fail(http.StatusInternalServerError, "產生圖片連結失敗").Send()
+			return
+		}
+		savedAt := image.CreatedAt
+		item.SavedAt = &savedAt
+		items = append(items, item)
+	}
+
+	response.New(c).Success("OK").SetData(gin.H{
+		"images":      items,
+		"next_cursor": next,
+	}).Send()
+}
+
+// listImagesByKey 直接列出儲存空間中使用者目錄下的原圖，依 key 排序分頁
+func (sc *S3Controller) listImagesByKey(c *gin.Context, userID string, limit int) {
+	// 每張原圖最多另有縮圖與顯示用兩個版本，一次多取一些；湊滿 limit 張原圖後若還有下一張原圖，
+	// next_cursor 即為本頁最後一張原圖的 key（其後的縮圖等版本在下一頁會被略過）
+	ctx := c.Request.Context()
+	cursor := c.Query("cursor")
+	items := make([]ImageItem, 0, limit)
+	next := ""
+pages:
+	for {
+		objects, pageNext, err := sc.storage.List(ctx, storage.ImagePrefix(userID), cursor, limit*3)
+		if err != nil {
+			log.Error("列出圖片失敗 userID=%s err=%s", userID, err.Error())
+			response.New(c).Fail(http.StatusInternalServerError, "列出圖片失敗").Send()
+			return
+		}
+		keys := make(map[string]bool, len(objects))
+		for _, obj := range objects {
+			keys[obj.Key] = true
+		}
+		for _, obj := range objects {
+			if storage.IsVariantKey(obj.Key) {
+				continue
+			}
+			if len(items) == limit {
+				next = items[len(items)-1].Key
+				break pages
+			}
+			item, err := sc.imageItem(ctx, keys, obj)
+			if err != nil {
+				log.Error("產生圖片連結失敗 key=%s err=%s", obj.Key, err.Error())
+				response.New(c).Fail(http.StatusInternalServerError, "產生圖片連結失敗").Send()
+				return
+			}
+			items = append(items, item)
+		}
+		if pageNext == "" {
+			break
+		}
+		cursor = pageNext
+	}
+
+	response.New(c).Success("OK").SetData(gin.H{
+		"images":      items,
+		"next_cursor": next,
+	}).Send()
+}
+
+// imageItem 組出原圖的列表項目（原圖與縮圖的簽章網址）；listed 為同一頁已列出的 key，可為 nil
+func (sc *S3Controller) imageItem(ctx context.Context, listed map[string]bool, obj storage.Object) (ImageItem, error) {
+	url, err := sc.storage.SignedURL(ctx, obj.Key, 1*time.Hour)
+	if err != nil {
+		return ImageItem{}, err
+	}
+	item := ImageItem{Key: obj.Key, Size: obj.Size, LastModified: obj.LastModified, URL: url}
+	if thumbKey := storage.VariantKey(obj.Key, storage.VariantThumb); sc.hasObject(ctx, listed, thumbKey) {
+		item.ThumbnailURL, _ = sc.storage.SignedURL(ctx, thumbKey, 1*time.Hour)
+	}
+	return item, nil
+}
+
+// encodeImageCursor 以照片的儲存時間與 key 組成下一頁的 cursor
+func encodeImageCursor(image models.UserImage) string {
+	raw := strconv.FormatInt(image.CreatedAt.UnixNano(), 10) + ":" + image.S3Key
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeImageCursor 解析 encodeImageCursor 產生的 cursor
+func decodeImageCursor(cursor string) (*models.UserImage, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	nanos, key, ok := strings.Cut(string(raw), ":")
+	if !ok || key == "" {
+		return nil, errors.New("cursor 缺少 key")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserImage{S3Key: key, CreatedAt: time.Unix(0, n)}, nil
+}
+
+// imageOwner 以 JWT 的 UserId claim（由 middlewares.Auth 寫入 context）作為圖片擁有者的 LINE userID；
+// 請求另外指定的 requested 不為空時必須與 claim 相同。檢查失敗時回 403 並回傳 false
+func imageOwner(c *gin.Context, requested string) (string, bool) {
+	claim, _ := c.Get("UserId")
+	userID, _ := claim.(string)
+	if userID == "" || strings.ContainsAny(userID, "/.") {
+		response.New(c).Fail(http.StatusForbidden, "Token 未包含有效的使用者").Send()
+		return "", false
+	}
+	if requested != "" && requested != userID {
+		response.New(c).Fail(http.StatusForbidden, "無權限存取其他使用者的圖片").Send()
+		return "", false
+	}
+	return userID, true
+}
+
+// hasObject key 是否存在：同一頁列出的物件直接查表，否則（縮圖落在下一頁）以 Head 確認
+func (sc *S3Controller) hasObject(ctx context.Context, listed map[string]bool, key string) bool {
+	if listed[key] {
+		return true
+	}
+	_, err := sc.storage.Head(ctx, key)
+	return err == nil
+}
+
+// DeleteImageReq 刪除圖片的請求
+type DeleteImageReq struct {
+	// 可省略；有帶時必須與 JWT 的 UserId claim 相同
+	UserID string `json:"user_id"`
+	// 原圖的 key（縮圖等版本會一併刪除）
+	S3Key string `json:"s3_key" binding:"required"`
+}
+
+// DeleteImage 刪除圖片（原圖與縮圖、顯示用版本），並移除使用者餐點中引用這張圖片的紀錄（沒有照片剩下的餐點整筆刪除）；
+// 使用者為 JWT 的 UserId claim，s3_key 必須位於該使用者的目錄下
+// DELETE /s3/images
+// Body: {"s3_key": "food-images/U80b35e04529b5a8be1fc2b4545240e7d/7fbe3086e4c6aa8998c0d51c7c89622b00b712b7344393e38e38befe67acb456.jpg"}
+func (sc *S3Controller) DeleteImage(c *gin.Context) {
+	var req DeleteImageReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.New(c).Fail(http.StatusBadRequest, "s3_key 必填").Send()
+		return
+	}
+	userID, ok := imageOwner(c, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+	if !strings.HasPrefix(req.S3Key, storage.ImagePrefix(userID)) {
+		response.New(c).Fail(http.StatusForbidden, "無權限刪除此圖片").Send()
+		return
+	}
+	if storage.IsVariantKey(req.S3Key) {
+		response.New(c).Fail(http.StatusBadRequest, "s3_key 須為原圖的 key").Send()
+		return
+	}
+
+	ctx := c.Request.Context()
+	_, headErr := sc.storage.Head(ctx, req.S3Key)
+	if headErr != nil && !errors.Is(headErr, storage.ErrNotFound) {
+		log.Error("取得圖片資訊失敗 key=%s err=%s", req.S3Key, headErr.Error())
+		response.New(c).Fail(http.StatusInternalServerError, "刪除圖片失敗").Send()
+		return
+	}
+	meals := 0
+	if sc.db != nil {
+		affected, _, err := sc.db.DeleteMealImage(req.UserID, req.S3Key, 0)
+		if err != nil {
+			log.Error("移除餐點照片失敗 userID=%s key=%s err=%s", req.UserID, req.S3Key, err.Error())
+			response.New(c).Fail(http.StatusInternalServerError, "刪除圖片失敗").Send()
+			return
+		}
+		meals = affected
+	}
+	if errors.Is(headErr, storage.ErrNotFound) && meals == 0 {
+		response.New(c).Fail(http.StatusNotFound, "圖片不存在").Send()
+		return
+	}
+
+	if err := storage.DeleteImage(ctx, sc.storage, req.S3Key); err != nil {
+		log.Error("刪除圖片失敗 key=%s err=%s", req.S3Key, err.Error())
+		response.New(c).Fail(http.StatusInternalServerError, "刪除圖片失敗").Send()
+		return
+	}
+	log.Info("已刪除圖片 userID=%s key=%s meals=%d", req.UserID, req.S3Key, meals)
+	response.New(c).Success("OK").SetData(gin.H{"s3_key": req.S3Key, "meals": meals}).Send()
+}
+
+// LocalFile 回傳本機儲存的檔案
+// GET /storage/files/{key}?expires=<unix 秒>&signature=<HMAC-SHA256>（由 storage.LocalStorage.SignedURL 產生）
+func (sc *S3Controller) LocalFile(c *gin.Context) {
+	local, ok := sc.storage.(*storage.LocalStorage)
+	if !ok {
+		response.New(c).Fail(http.StatusNotFound, "檔案不存在").Send()
+		return
+	}
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := local.Verify(key, c.Query("expires"), c.Query("signature"), time.Now()); err != nil {
+		response.New(c).Fail(http.StatusForbidden, "簽章無效或已過期").Send()
+		return
+	}
+
+	body, obj, err := local.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			response.New(c).Fail(http.StatusNotFound, "檔案不存在").Send()
+			return
+		}
+		log.Error("讀取本機檔案失敗 key=%s err=%s", key, err.Error())
+		response.New(c).Fail(http.StatusInternalServerError, "讀取檔案失敗").Send()
+		return
+	}
+	defer body.Close()
+
+	if obj.ContentType != "" {
+		c.Header("Content-Type", obj.ContentType)
+	}
+	if rs, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, path.Base(key), obj.LastModified, rs)
+		return
+	}
+	c.DataFromReader(http.StatusOK, obj.Size, obj.ContentType, body, nil)
 }
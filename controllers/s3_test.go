@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"project/models"
+	"project/services/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listImagesPage 以 userID 的身分呼叫 ListImages，回傳本頁的 key 與 next_cursor
+func listImagesPage(t *testing.T, sc *S3Controller, userID, query string) ([]string, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/s3/images?"+query, nil)
+	c.Set("UserId", userID)
+	sc.ListImages(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data struct {
+			Images     []ImageItem `json:"images"`
+			NextCursor string      `json:"next_cursor"`
+		} `json:"Data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v body=%s", err, w.Body.String())
+	}
+	keys := make([]string, 0, len(body.Data.Images))
+	for _, item := range body.Data.Images {
+		keys = append(keys, item.Key)
+	}
+	return keys, body.Data.NextCursor
+}
+
+func TestListImagesPaging(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st, err := storage.NewLocalStorage(t.TempDir(), "http://localhost", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	const userID = "U1"
+	// 7 張原圖，只有偶數張有縮圖與顯示用版本（模擬舊圖片沒有版本）
+	want := make([]string, 0, 7)
+	for i := 0; i < 7; i++ {
+		key := fmt.Sprintf("%s%02d.jpg", storage.ImagePrefix(userID), i)
+		keys := []string{key}
+		if i%2 == 0 {
+			keys = append(keys, storage.VariantKey(key, storage.VariantThumb), storage.VariantKey(key, storage.VariantDisplay))
+		}
+		for _, k := range keys {
+			if err := st.Put(ctx, k, strings.NewReader("x"), storage.PutOptions{ContentType: "image/jpeg"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want = append(want, key)
+	}
+	// 其他使用者的圖片不應出現
+	if err := st.Put(ctx, storage.ImagePrefix("U2")+"00.jpg", strings.NewReader("x"), storage.PutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	sc := NewS3Controller(st, nil)
+
+	tests := []struct {
+		limit int
+		pages [][]int
+	}{
+		{limit: 3, pages: [][]int{{0, 1, 2}, {3, 4, 5}, {6}}},
+		{limit: 1, pages: [][]int{{0}, {1}, {2}, {3}, {4}, {5}, {6}}},
+		{limit: 7, pages: [][]int{{0, 1, 2, 3, 4, 5, 6}}},
+		{limit: 100, pages: [][]int{{0, 1, 2, 3, 4, 5, 6}}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit_%d", tt.limit), func(t *testing.T) {
+			cursor := ""
+			for i, page := range tt.pages {
+				keys, next := listImagesPage(t, sc, userID, fmt.Sprintf("limit=%d&cursor=%s", tt.limit, url.QueryEscape(cursor)))
+				if len(keys) != len(page) {
+					t.Fatalf("page %d: got %v, want %d items", i, keys, len(page))
+				}
+				for j, idx := range page {
+					if keys[j] != want[idx] {
+						t.Errorf("page %d item %d = %s, want %s", i, j, keys[j], want[idx])
+					}
+				}
+				last := i == len(tt.pages)-1
+				if last && next != "" {
+					t.Errorf("page %d: next_cursor = %q, want empty", i, next)
+				}
+				if !last && next != keys[len(keys)-1] {
+					t.Errorf("page %d: next_cursor = %q, want last key %q", i, next, keys[len(keys)-1])
+				}
+				cursor = next
+			}
+		})
+	}
+}
+
+func TestListImagesRejectsOtherUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st, err := storage.NewLocalStorage(t.TempDir(), "http://localhost", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/s3/images?user_id=U2", nil)
+	c.Set("UserId", "U1")
+	NewS3Controller(st, nil).ListImages(c)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestImageCursor(t *testing.T) {
+	image := models.UserImage{
+		S3Key:     storage.ImagePrefix("U1") + "abc.jpg",
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 123456000, time.UTC),
+	}
+	decoded, err := decodeImageCursor(encodeImageCursor(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.S3Key != image.S3Key || !decoded.CreatedAt.Equal(image.CreatedAt) {
+		t.Errorf("decoded = %+v, want %+v", decoded, image)
+	}
+
+	for _, cursor := range []string{"not base64!", "bm9rZXk", "eDphLmpwZw"} {
+		if _, err := decodeImageCursor(cursor); err == nil {
+			t.Errorf("decodeImageCursor(%q) succeeded, want error", cursor)
+		}
+	}
+}
+
+func TestLocalFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	st, err := storage.NewLocalStorage(t.TempDir(), "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	const key = "food-images/U1/abc.jpg"
+	if err := st.Put(ctx, key, strings.NewReader("jpeg data"), storage.PutOptions{ContentType: "image/jpeg"}); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := st.SignedURL(ctx, key, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err := st.SignedURL(ctx, "food-images/U1/missing.jpg", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(signed)
+	query := u.Query()
+	query.Set("signature", strings.Repeat("0", len(query.Get("signature"))))
+	tampered := u.Path + "?" + query.Encode()
+
+	r := gin.New()
+	r.GET(storage.LocalFilesPath+"/*key", NewS3Controller(st, nil).LocalFile)
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantBody   string
+	}{
+		{"signed url", signed, http.StatusOK, "jpeg data"},
+		{"tampered signature", tampered, http.StatusForbidden, ""},
+		{"unsigned", u.Path, http.StatusForbidden, ""},
+		{"other key with same signature", strings.Replace(signed, "abc.jpg", "def.jpg", 1), http.StatusForbidden, ""},
+		{"missing file", missing, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d body=%s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantBody != "" {
+				if w.Body.String() != tt.wantBody || w.Header().Get("Content-Type") != "image/jpeg" {
+					t.Errorf("body = %q content-type = %q", w.Body.String(), w.Header().Get("Content-Type"))
+				}
+			}
+		})
+	}
+}
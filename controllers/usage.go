@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"project/models"
+	"project/services/log"
+	response "project/services/responses"
+)
+
+var (
+	usageController     *UsageController
+	usageControllerOnce sync.Once
+)
+
+func initUsageController() {
+	db, err := models.SharedDB()
+	if err != nil {
+		log.Error("用量報表：連線資料庫失敗 err=%s", err.Error())
+		return
+	}
+	usageController = NewUsageController(db)
+}
+
+// UsageController 辨識用量與費用報表（管理用）
+type UsageController struct {
+	db *models.DBManager
+}
+
+// NewUsageController 建立用量報表控制器
+func NewUsageController(db *models.DBManager) *UsageController {
+	return &UsageController{db: db}
+}
+
+// UsageReportHandler 供 route 註冊用：觸發時才取得共用的資料庫連線，未設定則回 503
+func UsageReportHandler(c *gin.Context) {
+	usageControllerOnce.Do(initUsageController)
+	if usageController == nil {
+		response.New(c).Fail(http.StatusServiceUnavailable, "資料庫未設定").Send()
+		return
+	}
+	usageController.Report(c)
+}
+
+// Report 彙總期間內的辨識呼叫次數、token 用量與估算費用
+// GET /admin/usage?from=2026-01-01&to=2026-01-31&group_by=day
+// from / to 為台北時間的日期（皆包含當天，預設最近 7 天）；group_by 為 day（預設）/ model / user
+func (uc *UsageController) Report(c *gin.Context) {
+	from, to, err := models.UsageReportRange(c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		response.New(c).Fail(http.StatusBadRequest, err.Error()).Send()
+		return
+	}
+	groupBy := models.UsageGroupBy(c.DefaultQuery("group_by", string(models.UsageByDay)))
+	if !groupBy.Valid() {
+		response.New(c).Fail(http.StatusBadRequest, "group_by 須為 day / model / user").Send()
+		return
+	}
+
+	rows, err := uc.db.RecognitionUsageReport(from, to, groupBy)
+	if err != nil {
+		log.Error("用量報表：查詢失敗 err=%s", err.Error())
+		response.New(c).Fail(http.StatusInternalServerError, "查詢用量失敗").Send()
+		return
+	}
+	response.New(c).Success("OK").SetData(gin.H{
+		"from":     from.Format("2006-01-02"),
+		"to":       to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"group_by": groupBy,
+		"rows":     rows,
+		"total":    models.SumUsage(rows),
+	}).Send()
+}
@@ -1,8 +1,10 @@
 package cron
 
 import (
+	linebotsvc "project/services/linebot"
 	"project/services/log"
 	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
@@ -11,7 +13,8 @@ import (
 var cronjob *cron.Cron
 var cronMutex sync.Mutex
 
-func Run() {
+// Run 建立並啟動排程；lineService 為 nil 時（LINE 未設定）不註冊與 LINE 相關的排程
+func Run(lineService *linebotsvc.LineBotService) {
 	cronMutex.Lock()
 	defer cronMutex.Unlock()
 
@@ -32,6 +35,14 @@ func Run() {
 	//			  │ │ │ │ │ │
 	// c.AddFunc("5 * * * * *", func())
 	ctx := map[string]error{}
+	if lineService != nil {
+		// 每分鐘檢查一次是否有使用者到達每日摘要推播時間（依各使用者時區）
+		if _, err := c.AddFunc("0 * * * * *", func() {
+			lineService.SendDailySummaries(time.Now())
+		}); err != nil {
+			ctx["dailySummary"] = err
+		}
+	}
 	if viper.GetString("ENV") == "prod" {
 
 	}
@@ -87,7 +87,7 @@ func App(HttpServer *gin.Engine) {
 	}
 
 	// 初始化 LINE Bot Service 與 Controller，並透過 middleware 注入到 context
-	// LineBotService 使用 project/services/imageai 進行圖片食物辨識（resize、openai、context）
+	// LineBotService 使用 project/services/imageai 進行圖片食物辨識（resize、辨識供應商、context）
 	lineService, err := linebotsvc.NewLineBotServiceFromEnv()
 	if err != nil {
 		log.Error("初始化 LINE Bot 服務失敗: %v", err)
@@ -140,7 +140,7 @@ func App(HttpServer *gin.Engine) {
 	)
 
 	// 執行排程
-	go cron.Run()
+	go cron.Run(lineService)
 	// 注冊路由
 	routes.Setup(HttpServer)
 
@@ -150,10 +150,11 @@ func App(HttpServer *gin.Engine) {
 		resp.Fail(http.StatusNotFound, "路由不存在").Send()
 	})
 
-	startServer(HttpServer, port)
+	startServer(HttpServer, port, lineService)
 }
 
-func startServer(router *gin.Engine, port string) {
+// startServer 啟動 HTTP 服務，收到 SIGINT / SIGTERM 時先停止接收請求，再等待 LINE 事件佇列處理完畢
+func startServer(router *gin.Engine, port string, lineService *linebotsvc.LineBotService) {
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", port),
 		Handler: router,
@@ -176,5 +177,17 @@ func startServer(router *gin.Engine, port string) {
 	if err := srv.Shutdown(ctx); err != nil {
 		fmt.Printf("Server forced to shutdown: %s\n", err.Error())
 	}
+	if lineService != nil {
+		drainTimeout := viper.GetDuration("Line.Drain.Timeout")
+		if drainTimeout <= 0 {
+			drainTimeout = 30 * time.Second
+		}
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer drainCancel()
+		fmt.Println("Draining LINE events...")
+		if err := lineService.Shutdown(drainCtx); err != nil {
+			fmt.Printf("LINE events drain timeout: %s\n", err.Error())
+		}
+	}
 	fmt.Println("Server exiting")
 }
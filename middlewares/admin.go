@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"project/services/log"
+	response "project/services/responses"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// adminTokenHeader 管理 API 的權杖 header
+const adminTokenHeader = "X-Admin-Token"
+
+// Admin 管理 API 的權限檢查（接在 Auth 之後）：X-Admin-Token 須與 ADMIN_TOKEN 相同；
+// 未設定 ADMIN_TOKEN 時一律拒絕，避免任何持有 JWT 的使用者讀取所有人的資料
+func Admin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		expected := viper.GetString("Admin.Token")
+		if expected == "" {
+			log.Warn("管理 API 未設定 ADMIN_TOKEN，拒絕請求 %s", ctx.Request.URL.Path)
+			response.New(ctx).Fail(http.StatusForbidden, "管理 API 未啟用").Send()
+			ctx.Abort()
+			return
+		}
+		token := ctx.GetHeader(adminTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			response.New(ctx).Fail(http.StatusForbidden, "需要管理員權限").Send()
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func TestAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		configured string
+		header     string
+		wantStatus int
+	}{
+		{"not configured", "", "", http.StatusForbidden},
+		{"not configured ignores header", "", "anything", http.StatusForbidden},
+		{"missing header", "secret", "", http.StatusForbidden},
+		{"wrong token", "secret", "secreT", http.StatusForbidden},
+		{"valid token", "secret", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("Admin.Token", tt.configured)
+			defer viper.Set("Admin.Token", "")
+
+			r := gin.New()
+			r.GET("/admin/ping", Admin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+			req := httptest.NewRequest(http.MethodGet, "/admin/ping", nil)
+			if tt.header != "" {
+				req.Header.Set(adminTokenHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -36,3 +36,13 @@ func WebhookFromContext(c *gin.Context) {
 	}
 	lc.Webhook(c)
 }
+
+// LineMetricsFromContext 從 context 取得 LineController 並回傳事件佇列指標
+func LineMetricsFromContext(c *gin.Context) {
+	lc := GetLineController(c)
+	if lc == nil {
+		c.JSON(500, gin.H{"error": "LineController not configured"})
+		return
+	}
+	lc.Metrics(c)
+}
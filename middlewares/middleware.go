@@ -43,14 +43,14 @@ func Auth() gin.HandlerFunc {
 		JwtSecret := viper.GetString("Server.JwtKey")
 		token, err := jwt.Parse(authorization, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				log.Error("token err :", token.Header["alg"])
+				log.Error("token err : %v", token.Header["alg"])
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
 			return []byte(JwtSecret), nil
 		})
 
 		if err != nil || !token.Valid {
-			log.Error("token err :", err.Error())
+			log.Error("token err : %v", err)
 			resp.Fail(http.StatusUnauthorized, "無效的 Token").Send()
 			ctx.Abort()
 			return
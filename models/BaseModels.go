@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"gorm.io/driver/postgres"
@@ -27,8 +28,18 @@ type DBManager struct {
 	SqlDBs  []*sql.DB
 }
 
-// PostgresNew 取得讀寫分離的資料庫連線（目前主從共用同一組環境變數）
+// PostgresNew 取得讀寫分離的資料庫連線（目前主從共用同一組環境變數），連線失敗時 panic
 func PostgresNew() *DBManager {
+	manager, err := PostgresNewWithError()
+	if err != nil {
+		//log.Error("建立資料庫錯誤: %s", err.Error())
+		panic(err)
+	}
+	return manager
+}
+
+// PostgresNewWithError 同 PostgresNew，但連線失敗時回傳錯誤，供可降級的服務使用
+func PostgresNewWithError() (*DBManager, error) {
 	// 使用環境變數讀取主庫配置，對應 .env / .env copy
 	writeConfig := &DBConfig{
 		Hostname: getEnv("POSTGRES_HOST", "localhost"),
@@ -53,12 +64,22 @@ func PostgresNew() *DBManager {
 		Port:     writeConfig.Port,
 	}
 
-	manager, err := NewDBManagerWithReplication(writeConfig, readConfig)
-	if err != nil {
-		//log.Error("建立資料庫錯誤: %s", err.Error())
-		panic(err)
-	}
-	return manager
+	return NewDBManagerWithReplication(writeConfig, readConfig)
+}
+
+var (
+	sharedDB     *DBManager
+	sharedDBErr  error
+	sharedDBOnce sync.Once
+)
+
+// SharedDB 取得整個程式共用的資料庫連線（第一次呼叫時建立），LINE Bot 與各 API 共用同一組連線池；
+// 連線失敗時之後的呼叫都回傳同一個錯誤
+func SharedDB() (*DBManager, error) {
+	sharedDBOnce.Do(func() {
+		sharedDB, sharedDBErr = PostgresNewWithError()
+	})
+	return sharedDB, sharedDBErr
 }
 
 // NewDBManagerWithReplication 創建讀寫分離的資料庫管理器
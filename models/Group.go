@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Group Bot 加入的群組或多人聊天室
+type Group struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	GroupID   string     `gorm:"size:64;uniqueIndex;not null" json:"group_id"` // LINE groupId 或 roomId
+	Type      string     `gorm:"size:16;not null" json:"type"`                 // group / room
+	Active    bool       `gorm:"not null;index" json:"active"`                 // false 表示 Bot 已離開
+	JoinedAt  *time.Time `json:"joined_at"`
+	LeftAt    *time.Time `json:"left_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName 指定資料表名稱
+func (Group) TableName() string {
+	return "groups"
+}
+
+// LeaderboardEntry 群組排行榜的一列
+type LeaderboardEntry struct {
+	LineUserID string  `json:"line_user_id"`
+	Meals      int64   `json:"meals"`
+	TotalKcal  float64 `json:"total_kcal"`
+}
+
+// UpsertGroup 以 group_id 新增或重新啟用群組（Bot 加入時）
+func (db *DBManager) UpsertGroup(group *Group) error {
+	return db.GetWrite().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"type", "active", "joined_at", "left_at", "updated_at"}),
+	}).Create(group).Error
+}
+
+// DeactivateGroup 將群組標記為已離開
+func (db *DBManager) DeactivateGroup(groupID string) error {
+	now := time.Now()
+	return db.GetWrite().Model(&Group{}).
+		Where("group_id = ?", groupID).
+		Updates(map[string]interface{}{
+			"active":  false,
+			"left_at": &now,
+		}).Error
+}
+
+// GroupLeaderboard 統計群組成員在 [from, to) 期間於該群組記錄的餐數，依餐數、熱量排序
+func (db *DBManager) GroupLeaderboard(groupID string, from, to time.Time, limit int) ([]LeaderboardEntry, error) {
+	entries := make([]LeaderboardEntry, 0)
+	err := db.GetRead().Model(&Meal{}).
+		Select("line_user_id, COUNT(*) AS meals, COALESCE(SUM(total_kcal), 0) AS total_kcal").
+		Where("group_id = ? AND eaten_at >= ? AND eaten_at < ?", groupID, from, to).
+		Group("line_user_id").
+		Order("meals DESC, total_kcal DESC").
+		Limit(limit).
+		Scan(&entries).Error
+	return entries, err
+}
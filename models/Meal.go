@@ -0,0 +1,229 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Meal 使用者儲存的一餐（一張或一組辨識過的照片，或一段語音描述）
+type Meal struct {
+	ID           uint        `gorm:"primaryKey" json:"id"`
+	LineUserID   string      `gorm:"size:64;not null;index:idx_meals_user_eaten_at,priority:1" json:"line_user_id"`
+	EatenAt      time.Time   `gorm:"not null;index:idx_meals_user_eaten_at,priority:2" json:"eaten_at"`
+	GroupID      string      `gorm:"size:64;index" json:"group_id"` // 在群組 / 聊天室中記錄時為其 ID
+	MealType     string      `gorm:"size:16" json:"meal_type"`      // 早餐 / 午餐 / 晚餐 / 點心
+	S3Key        string      `gorm:"size:512" json:"s3_key"`        // 第一張照片（一組照片時其餘見 Images）
+	ContentID    string      `gorm:"size:64" json:"content_id"`     // LINE 圖片訊息 ID
+	TotalKcal    float64     `json:"total_kcal"`
+	TotalProtein float64     `json:"total_protein"`
+	TotalFat     float64     `json:"total_fat"`
+	TotalCarbs   float64     `json:"total_carbs"`
+	Items        []MealItem  `gorm:"foreignKey:MealID;constraint:OnDelete:CASCADE" json:"items"`
+	Images       []MealImage `gorm:"foreignKey:MealID;constraint:OnDelete:CASCADE" json:"images"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+// TableName 指定資料表名稱
+func (Meal) TableName() string {
+	return "meals"
+}
+
+// MealItem 一餐中辨識出的單項食物
+type MealItem struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	MealID     uint      `gorm:"not null;index" json:"meal_id"`
+	Name       string    `gorm:"size:255;not null" json:"name"`
+	Portion    string    `gorm:"size:255" json:"portion"`
+	Kcal       float64   `json:"kcal"`
+	Protein    float64   `json:"protein"`
+	Fat        float64   `json:"fat"`
+	Carbs      float64   `json:"carbs"`
+	Confidence float64   `json:"confidence"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定資料表名稱
+func (MealItem) TableName() string {
+	return "meal_items"
+}
+
+// MealImage 一餐的照片（LINE 一次傳送多張照片時，每張一筆，依 Position 排序）
+type MealImage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	MealID    uint      `gorm:"not null;index" json:"meal_id"`
+	S3Key     string    `gorm:"size:512;not null" json:"s3_key"`
+	ContentID string    `gorm:"size:64" json:"content_id"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定資料表名稱
+func (MealImage) TableName() string {
+	return "meal_images"
+}
+
+// CreateMeal 新增一餐與其食物明細、照片（gorm 會在同一個交易內一併寫入 Items、Images）
+func (db *DBManager) CreateMeal(meal *Meal) error {
+	return db.GetWrite().Create(meal).Error
+}
+
+// ListMeals 取得使用者在 [from, to) 期間的餐點（含食物明細），依用餐時間排序
+func (db *DBManager) ListMeals(lineUserID string, from, to time.Time) ([]Meal, error) {
+	meals := make([]Meal, 0)
+	err := db.GetRead().
+		Preload("Items").
+		Where("line_user_id = ? AND eaten_at >= ? AND eaten_at < ?", lineUserID, from, to).
+		Order("eaten_at ASC").
+		Find(&meals).Error
+	return meals, err
+}
+
+// UserImage 使用者餐點中的一張照片與最近一次儲存的時間
+type UserImage struct {
+	S3Key     string
+	CreatedAt time.Time
+}
+
+// ListUserImages 依最近儲存時間由新到舊列出使用者餐點的照片（相同內容的照片共用 key，只列一次），最多 limit 筆；
+// after 為上一頁最後一筆（第一頁傳 nil），只列出排在它之後的照片
+func (db *DBManager) ListUserImages(lineUserID string, after *UserImage, limit int) ([]UserImage, error) {
+	images := make([]UserImage, 0, limit)
+	query := db.GetRead().Table("meal_images").
+		Select("meal_images.s3_key AS s3_key, MAX(meal_images.created_at) AS created_at").
+		Joins("JOIN meals ON meals.id = meal_images.meal_id").
+		Where("meals.line_user_id = ?", lineUserID).
+		Group("meal_images.s3_key")
+	if after != nil {
+		query = query.Having("(MAX(meal_images.created_at), meal_images.s3_key) < (?, ?)", after.CreatedAt, after.S3Key)
+	}
+	err := query.Order("created_at DESC, s3_key DESC").Limit(limit).Scan(&images).Error
+	return images, err
+}
+
+// LatestMealWithImage 取得使用者最近儲存、有照片的一餐（含照片），沒有時回傳 gorm.ErrRecordNotFound
+func (db *DBManager) LatestMealWithImage(lineUserID string) (*Meal, error) {
+	var meal Meal
+	err := db.GetRead().
+		Preload("Images", func(tx *gorm.DB) *gorm.DB { return tx.Order("position ASC") }).
+		Where("line_user_id = ? AND s3_key <> ''", lineUserID).
+		Order("created_at DESC, id DESC").
+		First(&meal).Error
+	if err != nil {
+		return nil, err
+	}
+	return &meal, nil
+}
+
+// DeleteMealImage 在同一個交易內移除使用者餐點中的某張照片（mealID 為 0 時移除所有餐點中的這張照片）：
+// 刪除對應的 meal_images；沒有照片剩下的餐點連同食物明細一併刪除，否則將 meals.s3_key 改為剩下的第一張。
+// affected 為受影響的餐點數；remaining 為使用者其他餐點仍引用這張照片的數量（相同內容的照片共用 key，為 0 時才可刪除物件）。
+func (db *DBManager) DeleteMealImage(lineUserID, s3Key string, mealID uint) (affected int, remaining int64, err error) {
+	err = db.GetWrite().Transaction(func(tx *gorm.DB) error {
+		referencing := func() *gorm.DB {
+			return tx.Model(&Meal{}).Where("line_user_id = ? AND (s3_key = ? OR id IN (?))",
+				lineUserID, s3Key, tx.Model(&MealImage{}).Select("meal_id").Where("s3_key = ?", s3Key))
+		}
+
+		var mealIDs []uint
+		query := referencing()
+		if mealID != 0 {
+			query = query.Where("id = ?", mealID)
+		}
+		if err := query.Pluck("id", &mealIDs).Error; err != nil {
+			return err
+		}
+		for _, id := range mealIDs {
+			if err := tx.Where("meal_id = ? AND s3_key = ?", id, s3Key).Delete(&MealImage{}).Error; err != nil {
+				return err
+			}
+			var first MealImage
+			result := tx.Where("meal_id = ?", id).Order("position ASC").Limit(1).Find(&first)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				if err := tx.Model(&Meal{}).Where("id = ?", id).Update("s3_key", first.S3Key).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Where("meal_id = ?", id).Delete(&MealItem{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&Meal{}, id).Error; err != nil {
+				return err
+			}
+		}
+		affected = len(mealIDs)
+		return referencing().Count(&remaining).Error
+	})
+	return affected, remaining, err
+}
+
+// GetMeal 取得使用者的某一餐（含照片），不存在或不屬於該使用者時回傳 gorm.ErrRecordNotFound
+func (db *DBManager) GetMeal(lineUserID string, mealID uint) (*Meal, error) {
+	var meal Meal
+	err := db.GetRead().
+		Preload("Images", func(tx *gorm.DB) *gorm.DB { return tx.Order("position ASC") }).
+		Where("line_user_id = ? AND id = ?", lineUserID, mealID).
+		First(&meal).Error
+	if err != nil {
+		return nil, err
+	}
+	return &meal, nil
+}
+
+// DeleteMeal 在同一個交易內刪除使用者的某一餐與其食物明細、照片紀錄，不存在時回傳 gorm.ErrRecordNotFound。
+// orphaned 為使用者其他餐點已不再引用的照片 key（相同內容的照片共用 key），呼叫端可再刪除儲存空間中的物件。
+func (db *DBManager) DeleteMeal(lineUserID string, mealID uint) (orphaned []string, err error) {
+	err = db.GetWrite().Transaction(func(tx *gorm.DB) error {
+		var meal Meal
+		if err := tx.Preload("Images").Where("line_user_id = ? AND id = ?", lineUserID, mealID).First(&meal).Error; err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(meal.Images)+1)
+		seen := make(map[string]bool)
+		for _, key := range append([]string{meal.S3Key}, imageKeys(meal.Images)...) {
+			if key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+
+		if err := tx.Where("meal_id = ?", meal.ID).Delete(&MealImage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("meal_id = ?", meal.ID).Delete(&MealItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&Meal{}, meal.ID).Error; err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			var remaining int64
+			err := tx.Model(&Meal{}).Where("line_user_id = ? AND (s3_key = ? OR id IN (?))",
+				lineUserID, key, tx.Model(&MealImage{}).Select("meal_id").Where("s3_key = ?", key)).
+				Count(&remaining).Error
+			if err != nil {
+				return err
+			}
+			if remaining == 0 {
+				orphaned = append(orphaned, key)
+			}
+		}
+		return nil
+	})
+	return orphaned, err
+}
+
+// imageKeys 取出照片的 key
+func imageKeys(images []MealImage) []string {
+	keys := make([]string, 0, len(images))
+	for _, image := range images {
+		keys = append(keys, image.S3Key)
+	}
+	return keys
+}
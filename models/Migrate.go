@@ -0,0 +1,13 @@
+package models
+
+// Migrate 建立 / 更新服務所需的資料表
+func (db *DBManager) Migrate() error {
+	return db.GetWrite().AutoMigrate(
+		&User{},
+		&Meal{},
+		&MealItem{},
+		&MealImage{},
+		&Group{},
+		&RecognitionUsage{},
+	)
+}
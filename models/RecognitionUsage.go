@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecognitionUsage 一次食物辨識呼叫的用量與估算費用（快取命中也會記錄，token 與費用為 0）
+type RecognitionUsage struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	LineUserID   string    `gorm:"size:64;not null;index" json:"line_user_id"`
+	GroupID      string    `gorm:"size:64" json:"group_id"`
+	Source       string    `gorm:"size:16" json:"source"`       // image / audio
+	Provider     string    `gorm:"size:32" json:"provider"`     // openai / gemini / anthropic，快取命中時為空
+	Model        string    `gorm:"size:128;index" json:"model"` // 實際回應的模型版本，快取命中時為空
+	Images       int       `json:"images"`                      // 本次辨識的圖片張數（語音為 0）
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	LatencyMs    int64     `json:"latency_ms"` // 含重試與快取查詢的總耗時
+	CostUSD      float64   `json:"cost_usd"`   // 依模型單價估算的費用（美元）
+	CacheHit     bool      `gorm:"not null;default:false" json:"cache_hit"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定資料表名稱
+func (RecognitionUsage) TableName() string {
+	return "recognition_usages"
+}
+
+// UsageGroupBy 用量報表的彙總維度
+type UsageGroupBy string
+
+const (
+	UsageByDay   UsageGroupBy = "day"   // 依日期（台北時間）
+	UsageByModel UsageGroupBy = "model" // 依模型
+	UsageByUser  UsageGroupBy = "user"  // 依使用者
+)
+
+// usageLocation 用量報表的日界線時區
+var usageLocation = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return time.FixedZone("Asia/Taipei", 8*60*60)
+	}
+	return loc
+}()
+
+// usageGroupColumns 各彙總維度對應的 SQL 欄位
+var usageGroupColumns = map[UsageGroupBy]string{
+	UsageByDay:   "to_char(created_at AT TIME ZONE 'Asia/Taipei', 'YYYY-MM-DD')",
+	UsageByModel: "model",
+	UsageByUser:  "line_user_id",
+}
+
+// Valid 是否為支援的彙總維度
+func (g UsageGroupBy) Valid() bool {
+	_, ok := usageGroupColumns[g]
+	return ok
+}
+
+// UsageSummary 用量報表的一列
+type UsageSummary struct {
+	Key          string  `json:"key"` // 日期（YYYY-MM-DD）、模型名稱或 LINE userID
+	Calls        int64   `json:"calls"`
+	CacheHits    int64   `json:"cache_hits"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// CreateRecognitionUsage 新增一筆辨識用量
+func (db *DBManager) CreateRecognitionUsage(usage *RecognitionUsage) error {
+	return db.GetWrite().Create(usage).Error
+}
+
+// RecognitionUsageReport 彙總 [from, to) 期間的辨識用量；依日期時由舊到新排序，其餘依費用由高到低排序
+func (db *DBManager) RecognitionUsageReport(from, to time.Time, groupBy UsageGroupBy) ([]UsageSummary, error) {
+	column, ok := usageGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("不支援的彙總維度: %s", groupBy)
+	}
+	order := "cost_usd DESC, calls DESC"
+	if groupBy == UsageByDay {
+		order = "key ASC"
+	}
+	summaries := make([]UsageSummary, 0)
+	err := db.GetRead().Model(&RecognitionUsage{}).
+		Select(column+" AS key, COUNT(*) AS calls, "+
+			"COUNT(*) FILTER (WHERE cache_hit) AS cache_hits, "+
+			"COALESCE(SUM(input_tokens), 0) AS input_tokens, "+
+			"COALESCE(SUM(output_tokens), 0) AS output_tokens, "+
+			"COALESCE(SUM(cost_usd), 0) AS cost_usd, "+
+			"COALESCE(AVG(latency_ms), 0) AS avg_latency_ms").
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Group(column).
+		Order(order).
+		Scan(&summaries).Error
+	return summaries, err
+}
+
+// UsageReportRange 將報表的起訖日期（YYYY-MM-DD，台北時間，皆包含當天）轉為查詢區間 [from, to)；
+// 未指定時結束日為今天、起始日為結束日前 6 天（共 7 天）
+func UsageReportRange(fromDate, toDate string, now time.Time) (time.Time, time.Time, error) {
+	now = now.In(usageLocation)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, usageLocation)
+	if toDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", toDate, usageLocation)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("結束日期格式錯誤: %s", toDate)
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -6)
+	if fromDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", fromDate, usageLocation)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("起始日期格式錯誤: %s", fromDate)
+		}
+		start = t
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("起始日期不可晚於結束日期")
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+// SumUsage 加總報表各列（平均耗時依呼叫次數加權）
+func SumUsage(rows []UsageSummary) UsageSummary {
+	total := UsageSummary{Key: "total"}
+	var latency float64
+	for _, row := range rows {
+		total.Calls += row.Calls
+		total.CacheHits += row.CacheHits
+		total.InputTokens += row.InputTokens
+		total.OutputTokens += row.OutputTokens
+		total.CostUSD += row.CostUSD
+		latency += row.AvgLatencyMs * float64(row.Calls)
+	}
+	if total.Calls > 0 {
+		total.AvgLatencyMs = latency / float64(total.Calls)
+	}
+	return total
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// User LINE 使用者（加入好友時寫入，封鎖時標記為停用）
+type User struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	LineUserID      string     `gorm:"size:64;uniqueIndex;not null" json:"line_user_id"`
+	DisplayName     string     `gorm:"size:255" json:"display_name"`
+	PictureURL      string     `gorm:"size:1024" json:"picture_url"`
+	StatusMessage   string     `gorm:"size:1024" json:"status_message"`
+	Language        string     `gorm:"size:16" json:"language"`      // LINE 個人資料的語言
+	Locale          string     `gorm:"size:16" json:"locale"`        // 使用者自行設定的回覆語系，優先於 Language
+	Timezone        string     `gorm:"size:64" json:"timezone"`      // IANA 時區（「時區 Asia/Tokyo」設定），空值視為 Asia/Taipei
+	Active          bool       `gorm:"not null;index" json:"active"` // false 表示已封鎖，不再推播
+	FollowedAt      *time.Time `json:"followed_at"`
+	UnfollowedAt    *time.Time `json:"unfollowed_at"`
+	LastSummaryDate string     `gorm:"size:10" json:"last_summary_date"` // 最後推播每日摘要的日期（使用者時區 2006-01-02）
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// TableName 指定資料表名稱
+func (User) TableName() string {
+	return "users"
+}
+
+// UpsertUser 以 line_user_id 新增或更新使用者
+// withProfile 為 false 時（例如取得個人資料失敗）只更新啟用狀態，不覆寫既有的名稱、頭像與語系
+func (db *DBManager) UpsertUser(user *User, withProfile bool) error {
+	columns := []string{"active", "followed_at", "unfollowed_at", "updated_at"}
+	if withProfile {
+		columns = append(columns, "display_name", "picture_url", "status_message", "language")
+	}
+	return db.GetWrite().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "line_user_id"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).Create(user).Error
+}
+
+// DeactivateUser 將使用者標記為停用（封鎖 / 取消好友）
+func (db *DBManager) DeactivateUser(lineUserID string) error {
+	now := time.Now()
+	return db.GetWrite().Model(&User{}).
+		Where("line_user_id = ?", lineUserID).
+		Updates(map[string]interface{}{
+			"active":        false,
+			"unfollowed_at": &now,
+		}).Error
+}
+
+// SetUserLocale 設定使用者的回覆語系（使用者尚未寫入時一併建立）。
+// 新建立的使用者為停用狀態：群組中未加入好友的成員無法接收推播，需等 follow 事件才啟用
+func (db *DBManager) SetUserLocale(lineUserID, locale string) error {
+	return db.GetWrite().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "line_user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"locale", "updated_at"}),
+	}).Create(&User{LineUserID: lineUserID, Locale: locale, Active: false}).Error
+}
+
+// SetUserTimezone 設定使用者的 IANA 時區（使用者尚未寫入時一併建立，與 SetUserLocale 相同為停用狀態）
+func (db *DBManager) SetUserTimezone(lineUserID, timezone string) error {
+	return db.GetWrite().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "line_user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"timezone", "updated_at"}),
+	}).Create(&User{LineUserID: lineUserID, Timezone: timezone, Active: false}).Error
+}
+
+// GetUserByLineID 依 LINE userID 取得使用者，不存在時回傳 gorm.ErrRecordNotFound
+func (db *DBManager) GetUserByLineID(lineUserID string) (*User, error) {
+	var user User
+	if err := db.GetRead().Where("line_user_id = ?", lineUserID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListActiveUsers 取得所有啟用中（未封鎖）的使用者
+func (db *DBManager) ListActiveUsers() ([]User, error) {
+	users := make([]User, 0)
+	err := db.GetRead().Where("active = ?", true).Order("id ASC").Find(&users).Error
+	return users, err
+}
+
+// ClaimDailySummary 標記使用者當天的每日摘要已推播；若已被標記（例如其他 instance 已處理）回傳 false
+func (db *DBManager) ClaimDailySummary(userID uint, date string) (bool, error) {
+	result := db.GetWrite().Model(&User{}).
+		Where("id = ? AND (last_summary_date IS NULL OR last_summary_date <> ?)", userID, date).
+		Update("last_summary_date", date)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseDailySummary 推播失敗時清除當天的標記，讓下一次排程重試
+func (db *DBManager) ReleaseDailySummary(userID uint, date string) error {
+	return db.GetWrite().Model(&User{}).
+		Where("id = ? AND last_summary_date = ?", userID, date).
+		Update("last_summary_date", "").Error
+}
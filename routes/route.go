@@ -3,17 +3,28 @@ package routes
 import (
 	"project/controllers"
 	"project/middlewares"
+	"project/services/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Setup 註冊所有路由（/s3/getImage 觸發時才從環境變數判斷是否可用）
+// Setup 註冊所有路由（/s3/*、/storage/files 觸發時才從環境變數建立儲存後端）
 func Setup(r *gin.Engine) {
 	r.GET("/", controllers.Health)
 	r.POST("/s3/getImage", controllers.S3GetImageHandler)
+	// 使用者的圖片列表（需 JWT，使用者為 UserId claim）：?cursor=&limit=
+	r.GET("/s3/images", middlewares.Auth(), controllers.S3ListImagesHandler)
+	// 刪除使用者的圖片與相關餐點紀錄（需 JWT，使用者為 UserId claim）：{"s3_key"}
+	r.DELETE("/s3/images", middlewares.Auth(), controllers.S3DeleteImageHandler)
+	// 本機儲存的簽章網址（STORAGE_BACKEND=local 時由 /s3/getImage 產生）
+	r.GET(storage.LocalFilesPath+"/*key", controllers.LocalFileHandler)
 
 	// LINE Webhook（LineController 由 middleware.LineControllerMiddleware 注入）
 	// dev: https://f16e-118-232-75-172.ngrok-free.app/line/webhook
 	// prod: https://my-go-line-bot.zeabur.app/line/webhook
 	r.POST("/line/webhook", middlewares.WebhookFromContext)
+	// Webhook 事件佇列指標（需 JWT 與 X-Admin-Token）：佇列深度、處理中、已拒絕數量
+	r.GET("/line/metrics", middlewares.Auth(), middlewares.Admin(), middlewares.LineMetricsFromContext)
+	// 辨識用量與估算費用報表（需 JWT 與 X-Admin-Token）：?from=&to=&group_by=day|model|user
+	r.GET("/admin/usage", middlewares.Auth(), middlewares.Admin(), controllers.UsageReportHandler)
 }
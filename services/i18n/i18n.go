@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultLocale 無法判斷使用者語系或翻譯缺漏時使用的語系
+const DefaultLocale = "zh-TW"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// catalogs 語系 → 訊息 key → 文字（可含 fmt 格式符號），啟動時由內嵌的 locales/*.json 載入
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]map[string]string {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("讀取語系檔失敗: %v", err))
+	}
+	out := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("讀取語系檔 %s 失敗: %v", entry.Name(), err))
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("語系檔 %s 格式錯誤: %v", entry.Name(), err))
+		}
+		out[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	return out
+}
+
+// Supported 是否有此語系的訊息檔
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Normalize 將 LINE 個人資料的 language（BCP 47，例如 zh-Hant、zh-TW、ja、en-US）
+// 或使用者輸入的語系名稱轉成支援的語系；不支援時回傳空字串
+func Normalize(lang string) string {
+	l := strings.ToLower(strings.TrimSpace(lang))
+	switch {
+	case l == "":
+		return ""
+	case strings.HasPrefix(l, "zh"), l == "中文", l == "繁體中文":
+		return "zh-TW"
+	case strings.HasPrefix(l, "ja"), l == "日本語", l == "日文":
+		return "ja"
+	case strings.HasPrefix(l, "en"), l == "英文":
+		return "en"
+	}
+	return ""
+}
+
+// T 取得語系的訊息並套用 args 格式化；該語系沒有此 key 時改用 DefaultLocale，仍沒有則回傳 key 本身
+func T(locale, key string, args ...any) string {
+	msg, ok := catalogs[locale][key]
+	if !ok {
+		msg, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
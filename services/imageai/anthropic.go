@@ -0,0 +1,123 @@
+package imageai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	defaultAnthropicModel   = "claude-3-5-haiku-latest"
+	anthropicVersion        = "2023-06-01"
+	anthropicToolName       = "record_food_nutrition"
+	anthropicMaxTokens      = 1024
+)
+
+// AnthropicRecognizer 使用 Anthropic Messages API（POST {baseURL}/v1/messages）辨識食物；
+// 以強制呼叫 tool 的方式取得符合 nutritionSchema 的 JSON。
+type AnthropicRecognizer struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewAnthropicRecognizer 建立 Anthropic 辨識；baseURL、model 為空時使用預設值
+func NewAnthropicRecognizer(baseURL, apiKey, model string) *AnthropicRecognizer {
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
+	}
+	if model == "" {
+		model = defaultAnthropicModel
+	}
+	return &AnthropicRecognizer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: defaultRequestTimeout},
+	}
+}
+
+// RecognizeImage 辨識圖片中的食物並估算營養成分
+func (r *AnthropicRecognizer) RecognizeImage(ctx context.Context, jpeg []byte) (*RecognitionResult, error) {
+	return r.RecognizeImages(ctx, [][]byte{jpeg})
+}
+
+// RecognizeImages 多張圖片以多個 image block 合併辨識（圖片在前、提示詞在後）
+func (r *AnthropicRecognizer) RecognizeImages(ctx context.Context, jpegs [][]byte) (*RecognitionResult, error) {
+	content := make([]map[string]any, 0, len(jpegs)+1)
+	for _, jpeg := range jpegs {
+		content = append(content, map[string]any{"type": "image", "source": map[string]any{
+			"type":       "base64",
+			"media_type": "image/jpeg",
+			"data":       base64.StdEncoding.EncodeToString(jpeg),
+		}})
+	}
+	content = append(content, map[string]any{"type": "text", "text": imagePromptFor(ctx, len(jpegs))})
+	return r.request(ctx, content)
+}
+
+// RecognizeText 將文字描述拆解成食物並估算營養成分
+func (r *AnthropicRecognizer) RecognizeText(ctx context.Context, text string) (*RecognitionResult, error) {
+	return r.request(ctx, []map[string]any{
+		{"type": "text", "text": textPromptFor(ctx) + text},
+	})
+}
+
+func (r *AnthropicRecognizer) request(ctx context.Context, content []map[string]any) (*RecognitionResult, error) {
+	body := map[string]any{
+		"model":      r.model,
+		"max_tokens": anthropicMaxTokens,
+		"messages": []map[string]any{
+			{"role": "user", "content": content},
+		},
+		"tools": []map[string]any{
+			{
+				"name":         anthropicToolName,
+				"description":  "記錄辨識出的食物與營養估算",
+				"input_schema": nutritionSchema,
+			},
+		},
+		"tool_choice": map[string]any{"type": "tool", "name": anthropicToolName},
+	}
+
+	var result struct {
+		Model string `json:"model"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+		Content []struct {
+			Type  string          `json:"type"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+	}
+	headers := map[string]string{
+		"x-api-key":         r.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+	if err := postJSON(ctx, r.client, "Anthropic", r.baseURL+"/v1/messages", headers, body, &result); err != nil {
+		return nil, err
+	}
+	for _, block := range result.Content {
+		if block.Type == "tool_use" && block.Name == anthropicToolName {
+			parsed, err := ParseRecognitionResult(string(block.Input))
+			if err != nil {
+				return nil, err
+			}
+			parsed.Usage = &Usage{
+				Provider:     "anthropic",
+				Model:        firstNonEmpty(result.Model, r.model),
+				InputTokens:  result.Usage.InputTokens,
+				OutputTokens: result.Usage.OutputTokens,
+			}
+			return parsed, nil
+		}
+	}
+	return nil, fmt.Errorf("Anthropic API 未回傳辨識結果")
+}
@@ -0,0 +1,136 @@
+package imageai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	"project/services/cache"
+	logsvc "project/services/log"
+	"project/services/redis"
+)
+
+const (
+	recognitionCachePrefix     = "imageai:recognition:"
+	defaultRecognitionCacheTTL = 24 * time.Hour
+	defaultRecognitionLRUSize  = 500
+)
+
+type noCacheKey struct{}
+
+// WithNoCache 這次辨識略過快取讀取（例如「重新辨識」），辨識結果仍會寫入快取取代舊的結果
+func WithNoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// noCache 判斷 ctx 是否指定略過快取讀取
+func noCache(ctx context.Context) bool {
+	skip, _ := ctx.Value(noCacheKey{}).(bool)
+	return skip
+}
+
+// CachingRecognizer 以圖片內容的 SHA-256（加上語系）快取辨識結果（使用者重傳同一張照片時不再呼叫模型）：
+// 優先存放於 Redis（多 instance 共用），Redis 不可用時改用容量有上限的記憶體 LRU。
+type CachingRecognizer struct {
+	next   Recognizer
+	redis  *redis.Client
+	lru    *cache.LRU
+	ttl    time.Duration
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCachingRecognizer 包裝 next；ttl <= 0 預設 24 小時，lruSize <= 0 預設 500 筆
+func NewCachingRecognizer(next Recognizer, redisClient *redis.Client, ttl time.Duration, lruSize int) *CachingRecognizer {
+	if ttl <= 0 {
+		ttl = defaultRecognitionCacheTTL
+	}
+	if lruSize <= 0 {
+		lruSize = defaultRecognitionLRUSize
+	}
+	return &CachingRecognizer{
+		next:  next,
+		redis: redisClient,
+		lru:   cache.NewLRU(lruSize, ttl),
+		ttl:   ttl,
+	}
+}
+
+// RecognizeImage 命中快取時回傳快取結果（CacheHit 為 true），否則呼叫下層辨識並寫入快取
+func (r *CachingRecognizer) RecognizeImage(ctx context.Context, jpeg []byte) (*RecognitionResult, error) {
+	sum := sha256.Sum256(jpeg)
+	return r.recognize(ctx, LocaleFrom(ctx)+":"+hex.EncodeToString(sum[:]), func() (*RecognitionResult, error) {
+		return r.next.RecognizeImage(ctx, jpeg)
+	})
+}
+
+// RecognizeImages 以各張圖片雜湊依序串接後再雜湊作為 key（同一組照片、同一順序才會命中）
+func (r *CachingRecognizer) RecognizeImages(ctx context.Context, jpegs [][]byte) (*RecognitionResult, error) {
+	if len(jpegs) == 1 {
+		return r.RecognizeImage(ctx, jpegs[0])
+	}
+	h := sha256.New()
+	for _, jpeg := range jpegs {
+		sum := sha256.Sum256(jpeg)
+		h.Write(sum[:])
+	}
+	return r.recognize(ctx, LocaleFrom(ctx)+":set:"+hex.EncodeToString(h.Sum(nil)), func() (*RecognitionResult, error) {
+		return r.next.RecognizeImages(ctx, jpegs)
+	})
+}
+
+// recognize 先查快取，未命中（或 ctx 指定略過快取）時執行 call 並寫入快取
+func (r *CachingRecognizer) recognize(ctx context.Context, key string, call func() (*RecognitionResult, error)) (*RecognitionResult, error) {
+	if noCache(ctx) {
+		logsvc.Info("辨識略過快取 key=%s", key)
+	} else if cached := r.get(key); cached != nil {
+		hits := r.hits.Add(1)
+		logsvc.Info("辨識快取命中 key=%s hits=%d misses=%d", key, hits, r.misses.Load())
+		cached.CacheHit = true
+		return cached, nil
+	}
+	misses := r.misses.Add(1)
+	logsvc.Info("辨識快取未命中 key=%s hits=%d misses=%d", key, r.hits.Load(), misses)
+
+	result, err := call()
+	if err != nil {
+		return nil, err
+	}
+	r.set(key, result)
+	return result, nil
+}
+
+// RecognizeText 文字描述不快取，直接交給下層辨識
+func (r *CachingRecognizer) RecognizeText(ctx context.Context, text string) (*RecognitionResult, error) {
+	return r.next.RecognizeText(ctx, text)
+}
+
+// get 先查 Redis，未命中或 Redis 不可用時再查記憶體 LRU（Redis 寫入失敗時結果會留在 LRU）
+func (r *CachingRecognizer) get(key string) *RecognitionResult {
+	if r.redis != nil && r.redis.IsAvailable() {
+		var result RecognitionResult
+		if err := r.redis.GetJSON(recognitionCachePrefix+key, &result); err == nil {
+			result.normalize() // 相容舊版未含 status 的快取
+			return &result
+		}
+	}
+	if v, ok := r.lru.Get(key); ok {
+		return v.(*RecognitionResult).Clone()
+	}
+	return nil
+}
+
+// set 寫入 Redis，Redis 不可用或寫入失敗時改存記憶體 LRU
+func (r *CachingRecognizer) set(key string, result *RecognitionResult) {
+	if r.redis != nil && r.redis.IsAvailable() {
+		err := r.redis.SetJSON(recognitionCachePrefix+key, result, r.ttl)
+		if err == nil {
+			r.lru.Delete(key) // 避免之後讀到較舊的記憶體結果
+			return
+		}
+		logsvc.Warn("辨識快取寫入 Redis 失敗，改用記憶體 err=%s", err.Error())
+	}
+	r.lru.Set(key, result.Clone())
+}
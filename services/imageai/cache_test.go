@@ -0,0 +1,50 @@
+package imageai
+
+import (
+	"context"
+	"testing"
+)
+
+// countingRecognizer 每次呼叫回傳不同熱量（第 n 次為 n*100），用來分辨結果是否來自快取
+type countingRecognizer struct {
+	FakeRecognizer
+	calls int
+}
+
+func (r *countingRecognizer) RecognizeImage(ctx context.Context, jpeg []byte) (*RecognitionResult, error) {
+	r.calls++
+	result := &RecognitionResult{Items: []FoodItem{{Name: "白飯", Kcal: float64(r.calls * 100)}}}
+	result.normalize()
+	return result, nil
+}
+
+func TestCachingRecognizer(t *testing.T) {
+	next := &countingRecognizer{}
+	r := NewCachingRecognizer(next, nil, 0, 0)
+	ctx := WithLocale(context.Background(), "zh-TW")
+	image := []byte("same image")
+
+	steps := []struct {
+		name      string
+		ctx       context.Context
+		wantKcal  float64
+		wantHit   bool
+		wantCalls int
+	}{
+		{"first call misses", ctx, 100, false, 1},
+		{"second call hits", ctx, 100, true, 1},
+		{"no cache skips read", WithNoCache(ctx), 200, false, 2},
+		{"fresh result replaces cache", ctx, 200, true, 2},
+		{"other locale misses", WithLocale(context.Background(), "en"), 300, false, 3},
+	}
+	for _, step := range steps {
+		result, err := r.RecognizeImage(step.ctx, image)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if result.Items[0].Kcal != step.wantKcal || result.CacheHit != step.wantHit || next.calls != step.wantCalls {
+			t.Errorf("%s: kcal=%v hit=%v calls=%d, want kcal=%v hit=%v calls=%d",
+				step.name, result.Items[0].Kcal, result.CacheHit, next.calls, step.wantKcal, step.wantHit, step.wantCalls)
+		}
+	}
+}
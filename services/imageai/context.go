@@ -1,56 +1,156 @@
 package imageai
 
 import (
-	"sync"
+	"os"
+	"strconv"
+	"strings"
 	"time"
+
+	"project/services/redis"
 )
 
 const contextTTL = 10 * time.Minute
 
-// UserImageContext 紀錄使用者上一則成功辨識的圖片，供儲存觸發使用。
+// context 的來源
+const (
+	SourceImage = "image" // 圖片辨識
+	SourceAudio = "audio" // 語音描述
+)
+
+// UserImageContext 紀錄使用者成功辨識的圖片與辨識結果，供儲存觸發使用。
+// 群組 / 聊天室中以 (GroupID, UserID) 區分，避免成員之間互相覆蓋。
 type UserImageContext struct {
-	UserID     string
-	ContentID  string
-	ReplyToken string
-	ExpiresAt  int64
+	UserID       string
+	GroupID      string   // 群組或聊天室 ID，一對一聊天為空
+	Source       string   // SourceImage / SourceAudio，空值視為圖片
+	ContentID    string   // LINE 訊息內容 ID（圖片或語音）；一組照片時為第一張
+	ContentIDs   []string // 一組照片（LINE ImageSet）的所有內容 ID，依傳送順序；單張時為空
+	ReplyToken   string
+	Note         string // 回覆卡片的附註（例如語音轉出的文字），澄清後重新回覆時沿用
+	Result       *RecognitionResult
+	RecognizedAt int64
+	ExpiresAt    int64
 }
 
-var (
-	mu      sync.RWMutex
-	contextMap = make(map[string]*UserImageContext)
-)
+// AllContentIDs 所有圖片的內容 ID（單張時只有 ContentID）
+func (c *UserImageContext) AllContentIDs() []string {
+	if len(c.ContentIDs) > 0 {
+		return c.ContentIDs
+	}
+	return []string{c.ContentID}
+}
+
+// ContextStore 保存使用者待儲存的辨識 context。每位使用者（群組中為每位成員）保留最近幾筆，
+// 最新一筆為「目前」的 context（文字「儲存」的對象），較舊的仍可由卡片上的按鈕依 ContentID 操作。
+type ContextStore interface {
+	// Set 新增或更新（同一 ContentID）context 並設為目前，效期 10 分鐘；RecognizedAt、ExpiresAt 由此設定
+	Set(c *UserImageContext)
+	// Get 取得目前的 context，沒有或已過期時回傳 nil
+	Get(userID, groupID string) *UserImageContext
+	// Find 依 ContentID 在近期紀錄中尋找 context，contentID 為空時等同 Get
+	Find(userID, groupID, contentID string) *UserImageContext
+	// Delete 移除指定 ContentID 的 context（儲存或捨棄後），contentID 為空時移除目前的 context
+	Delete(userID, groupID, contentID string)
+	// History 近期尚未過期的 context，新的在前
+	History(userID, groupID string) []*UserImageContext
+}
 
-// Set 儲存使用者成功辨識圖片的 context，效期 10 分鐘。
-func Set(userID, contentID, replyToken string) {
-	if userID == "" || contentID == "" {
-		return
+// contextKey 一對一聊天以 userID 為 key，群組 / 聊天室為 groupID:userID
+func contextKey(userID, groupID string) string {
+	if groupID == "" {
+		return userID
 	}
-	expiresAt := time.Now().Add(contextTTL).Unix()
-	mu.Lock()
-	defer mu.Unlock()
-	contextMap[userID] = &UserImageContext{
-		UserID:     userID,
-		ContentID:  contentID,
-		ReplyToken: replyToken,
-		ExpiresAt:  expiresAt,
+	return groupID + ":" + userID
+}
+
+// contextEntry 單一使用者的 context 紀錄（各 store 共用的操作邏輯，Redis 版以 JSON 整筆存放）
+type contextEntry struct {
+	Current string              `json:"current"` // 目前 context 的 ContentID，儲存 / 捨棄後清空
+	History []*UserImageContext `json:"history"` // 新的在前，最多 historySize 筆
+}
+
+// put 將 c 放到最前面並設為目前；同一 ContentID 視為更新（例如澄清後）
+func (e *contextEntry) put(c *UserImageContext, historySize int) {
+	history := make([]*UserImageContext, 0, historySize)
+	history = append(history, c)
+	for _, old := range e.History {
+		if old.ContentID != c.ContentID && len(history) < historySize {
+			history = append(history, old)
+		}
 	}
+	e.History = history
+	e.Current = c.ContentID
 }
 
-// Get 取得使用者上一則成功辨識的 context，若已過期則清除並回傳 nil。
-func Get(userID string) *UserImageContext {
-	if userID == "" {
-		return nil
+// find contentID 為空時回傳目前的 context
+func (e *contextEntry) find(contentID string, now int64) *UserImageContext {
+	if contentID == "" {
+		contentID = e.Current
 	}
-	now := time.Now().Unix()
-	mu.Lock()
-	defer mu.Unlock()
-	ctx, ok := contextMap[userID]
-	if !ok || ctx == nil {
+	if contentID == "" {
 		return nil
 	}
-	if ctx.ExpiresAt < now {
-		delete(contextMap, userID)
-		return nil
+	for _, c := range e.History {
+		if c.ContentID == contentID && c.ExpiresAt >= now {
+			return c
+		}
+	}
+	return nil
+}
+
+// remove contentID 為空時移除目前的 context
+func (e *contextEntry) remove(contentID string) {
+	if contentID == "" {
+		contentID = e.Current
+	}
+	if contentID == e.Current {
+		e.Current = ""
+	}
+	history := e.History[:0]
+	for _, c := range e.History {
+		if c.ContentID != contentID {
+			history = append(history, c)
+		}
+	}
+	e.History = history
+}
+
+// prune 清除過期的 context，回傳是否已無任何紀錄
+func (e *contextEntry) prune(now int64) bool {
+	history := e.History[:0]
+	for _, c := range e.History {
+		if c.ExpiresAt >= now {
+			history = append(history, c)
+		}
+	}
+	e.History = history
+	if e.find(e.Current, now) == nil {
+		e.Current = ""
+	}
+	return len(e.History) == 0
+}
+
+// stamp 設定 RecognizedAt（首次）與 ExpiresAt
+func stamp(c *UserImageContext, now time.Time) {
+	if c.RecognizedAt == 0 {
+		c.RecognizedAt = now.Unix()
+	}
+	c.ExpiresAt = now.Add(contextTTL).Unix()
+}
+
+// validContext Set 的最低要求
+func validContext(c *UserImageContext) bool {
+	return c != nil && c.UserID != "" && c.ContentID != ""
+}
+
+// NewContextStoreFromEnv 依 CONTEXT_STORE 建立 context store：redis（多 instance 部署，Redis 不可用時退回記憶體）
+// 或 memory（預設）。CONTEXT_MAX_SIZE、CONTEXT_HISTORY_SIZE 分別為記憶體版的使用者上限與每位使用者保留筆數。
+func NewContextStoreFromEnv(redisClient *redis.Client) ContextStore {
+	maxSize, _ := strconv.Atoi(os.Getenv("CONTEXT_MAX_SIZE"))
+	historySize, _ := strconv.Atoi(os.Getenv("CONTEXT_HISTORY_SIZE"))
+	memory := NewMemoryContextStore(maxSize, historySize)
+	if strings.ToLower(os.Getenv("CONTEXT_STORE")) == "redis" {
+		return NewRedisContextStore(redisClient, historySize, memory)
 	}
-	return ctx
+	return memory
 }
@@ -0,0 +1,166 @@
+package imageai
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	defaultContextMaxSize     = 10000
+	defaultContextHistorySize = 5
+	contextJanitorInterval    = time.Minute
+)
+
+// MemoryContextStore 單一 instance 使用的記憶體 context store：
+// 背景 janitor 定期清除過期紀錄，使用者數超過 maxSize 時淘汰最久未更新的使用者。
+type MemoryContextStore struct {
+	mu          sync.Mutex
+	maxSize     int
+	historySize int
+	order       *list.List              // 最近更新的在前，元素值為 key
+	entries     map[string]*memoryEntry // key 為 contextKey
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+type memoryEntry struct {
+	contextEntry
+	elem *list.Element
+}
+
+// NewMemoryContextStore 建立記憶體 context store 並啟動 janitor；maxSize、historySize <= 0 時使用預設值（10000、5）
+func NewMemoryContextStore(maxSize, historySize int) *MemoryContextStore {
+	if maxSize <= 0 {
+		maxSize = defaultContextMaxSize
+	}
+	if historySize <= 0 {
+		historySize = defaultContextHistorySize
+	}
+	s := &MemoryContextStore{
+		maxSize:     maxSize,
+		historySize: historySize,
+		order:       list.New(),
+		entries:     make(map[string]*memoryEntry),
+		stop:        make(chan struct{}),
+	}
+	go s.janitor()
+	return s
+}
+
+// Set 新增或更新 context
+func (s *MemoryContextStore) Set(c *UserImageContext) {
+	if !validContext(c) {
+		return
+	}
+	stamp(c, time.Now())
+	key := contextKey(c.UserID, c.GroupID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{elem: s.order.PushFront(key)}
+		s.entries[key] = entry
+	} else {
+		s.order.MoveToFront(entry.elem)
+	}
+	entry.put(c, s.historySize)
+
+	for len(s.entries) > s.maxSize {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(string))
+	}
+}
+
+// Get 取得目前的 context
+func (s *MemoryContextStore) Get(userID, groupID string) *UserImageContext {
+	return s.Find(userID, groupID, "")
+}
+
+// Find 依 ContentID 尋找 context
+func (s *MemoryContextStore) Find(userID, groupID, contentID string) *UserImageContext {
+	if userID == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[contextKey(userID, groupID)]
+	if !ok {
+		return nil
+	}
+	return entry.find(contentID, time.Now().Unix())
+}
+
+// Delete 移除 context
+func (s *MemoryContextStore) Delete(userID, groupID, contentID string) {
+	if userID == "" {
+		return
+	}
+	key := contextKey(userID, groupID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	entry.remove(contentID)
+	if len(entry.History) == 0 {
+		s.order.Remove(entry.elem)
+		delete(s.entries, key)
+	}
+}
+
+// History 近期尚未過期的 context
+func (s *MemoryContextStore) History(userID, groupID string) []*UserImageContext {
+	if userID == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[contextKey(userID, groupID)]
+	if !ok {
+		return nil
+	}
+	entry.prune(time.Now().Unix())
+	return append([]*UserImageContext(nil), entry.History...)
+}
+
+// Len 目前保存的使用者數
+func (s *MemoryContextStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Close 停止 janitor
+func (s *MemoryContextStore) Close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// janitor 定期清除過期紀錄，避免沒有再互動的使用者永遠占用記憶體
+func (s *MemoryContextStore) janitor() {
+	ticker := time.NewTicker(contextJanitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.cleanup()
+		}
+	}
+}
+
+func (s *MemoryContextStore) cleanup() {
+	now := time.Now().Unix()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, entry := range s.entries {
+		if entry.prune(now) {
+			s.order.Remove(entry.elem)
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package imageai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	logsvc "project/services/log"
+	"project/services/redis"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	contextRedisPrefix = "imageai:context:"
+	// contextUpdateRetries 其他 instance 同時修改同一位使用者的紀錄時，最多重試幾次
+	contextUpdateRetries = 5
+)
+
+// RedisContextStore 多 instance 共用的 context store：每位使用者一筆 JSON，TTL 與 context 效期相同。
+// 修改時以 WATCH / MULTI 讀取、修改再寫回，不同 instance 同時處理同一位使用者的事件時不會互相覆蓋。
+// Redis 不可用時改用記憶體 store（單一 instance 內仍可運作）。
+type RedisContextStore struct {
+	redis       *redis.Client
+	historySize int
+	fallback    *MemoryContextStore
+}
+
+// NewRedisContextStore 建立 Redis context store；historySize <= 0 時預設 5 筆
+func NewRedisContextStore(client *redis.Client, historySize int, fallback *MemoryContextStore) *RedisContextStore {
+	if historySize <= 0 {
+		historySize = defaultContextHistorySize
+	}
+	return &RedisContextStore{redis: client, historySize: historySize, fallback: fallback}
+}
+
+func (s *RedisContextStore) available() bool {
+	return s.redis != nil && s.redis.IsAvailable()
+}
+
+// load 讀取使用者的紀錄，不存在或讀取失敗時回傳空紀錄
+func (s *RedisContextStore) load(key string) *contextEntry {
+	var entry contextEntry
+	if err := s.redis.GetJSON(contextRedisPrefix+key, &entry); err != nil {
+		return &contextEntry{}
+	}
+	return &entry
+}
+
+// update 在 WATCH / MULTI 交易中讀取紀錄、以 modify 修改後寫回（已無任何 context 時刪除 key）；
+// 交易期間被其他 instance 修改時重新讀取並再套用一次 modify
+func (s *RedisContextStore) update(key string, modify func(entry *contextEntry)) {
+	redisKey := contextRedisPrefix + key
+	var err error
+	for i := 0; i < contextUpdateRetries; i++ {
+		err = s.redis.Watch(func(ctx context.Context, tx *goredis.Tx) error {
+			var entry contextEntry
+			data, err := tx.Get(ctx, redisKey).Bytes()
+			if err != nil && !errors.Is(err, goredis.Nil) {
+				return err
+			}
+			if err == nil && json.Unmarshal(data, &entry) != nil {
+				entry = contextEntry{}
+			}
+			modify(&entry)
+
+			empty := entry.prune(time.Now().Unix())
+			value, err := json.Marshal(&entry)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+				if empty {
+					pipe.Del(ctx, redisKey)
+				} else {
+					pipe.Set(ctx, redisKey, value, contextTTL)
+				}
+				return nil
+			})
+			return err
+		}, redisKey)
+		if !errors.Is(err, goredis.TxFailedErr) {
+			break
+		}
+	}
+	if err != nil {
+		logsvc.Error("context 寫入 Redis 失敗 key=%s err=%s", key, err.Error())
+	}
+}
+
+// Set 新增或更新 context
+func (s *RedisContextStore) Set(c *UserImageContext) {
+	if !s.available() {
+		s.fallback.Set(c)
+		return
+	}
+	if !validContext(c) {
+		return
+	}
+	stamp(c, time.Now())
+	s.update(contextKey(c.UserID, c.GroupID), func(entry *contextEntry) {
+		entry.put(c, s.historySize)
+	})
+}
+
+// Get 取得目前的 context
+func (s *RedisContextStore) Get(userID, groupID string) *UserImageContext {
+	return s.Find(userID, groupID, "")
+}
+
+// Find 依 ContentID 尋找 context
+func (s *RedisContextStore) Find(userID, groupID, contentID string) *UserImageContext {
+	if !s.available() {
+		return s.fallback.Find(userID, groupID, contentID)
+	}
+	if userID == "" {
+		return nil
+	}
+	return s.load(contextKey(userID, groupID)).find(contentID, time.Now().Unix())
+}
+
+// Delete 移除 context
+func (s *RedisContextStore) Delete(userID, groupID, contentID string) {
+	if !s.available() {
+		s.fallback.Delete(userID, groupID, contentID)
+		return
+	}
+	if userID == "" {
+		return
+	}
+	s.update(contextKey(userID, groupID), func(entry *contextEntry) {
+		entry.remove(contentID)
+	})
+}
+
+// History 近期尚未過期的 context
+func (s *RedisContextStore) History(userID, groupID string) []*UserImageContext {
+	if !s.available() {
+		return s.fallback.History(userID, groupID)
+	}
+	if userID == "" {
+		return nil
+	}
+	entry := s.load(contextKey(userID, groupID))
+	entry.prune(time.Now().Unix())
+	return entry.History
+}
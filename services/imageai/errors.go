@@ -0,0 +1,143 @@
+package imageai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind 辨識 API 錯誤的分類，決定是否重試與回覆使用者的訊息
+type ErrorKind string
+
+const (
+	ErrorKindQuota      ErrorKind = "quota"       // 額度或帳單用盡，重試無效
+	ErrorKindRateLimit  ErrorKind = "rate_limit"  // 請求過於頻繁（429），可稍後重試
+	ErrorKindTimeout    ErrorKind = "timeout"     // 連線或回應逾時
+	ErrorKindBadRequest ErrorKind = "bad_request" // 請求內容或金鑰有誤（4xx），重試無效
+	ErrorKindServer     ErrorKind = "server"      // 供應商端錯誤（5xx）或連線中斷，可重試
+	ErrorKindUnknown    ErrorKind = "unknown"
+)
+
+// APIError 辨識供應商回傳的錯誤
+type APIError struct {
+	Provider   string
+	Kind       ErrorKind
+	StatusCode int           // 連線層錯誤時為 0
+	RetryAfter time.Duration // 回應帶有 Retry-After 時的建議等待時間
+	Body       string        // 已遮蔽金鑰並截斷的回應內容
+	Err        error         // 連線層的原始錯誤
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s API %s: %v", e.Provider, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%s API 回傳 %d (%s)", e.Provider, e.StatusCode, e.Kind)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable 是否值得重試（限流、逾時、供應商端錯誤）
+func (e *APIError) Retryable() bool {
+	switch e.Kind {
+	case ErrorKindRateLimit, ErrorKindTimeout, ErrorKindServer:
+		return true
+	}
+	return false
+}
+
+// ErrorKindOf 取得錯誤分類；非 APIError 時以 context 錯誤判斷是否逾時
+func ErrorKindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorKindTimeout
+	}
+	return ErrorKindUnknown
+}
+
+// classifyStatus 依 HTTP 狀態碼與回應內容分類；各家的額度用盡有時也以 429 回傳，需看 body
+func classifyStatus(status int, body string) ErrorKind {
+	lower := strings.ToLower(body)
+	quota := strings.Contains(lower, "insufficient_quota") ||
+		strings.Contains(lower, "quota") ||
+		strings.Contains(lower, "billing") ||
+		strings.Contains(lower, "credit balance")
+	switch {
+	case status == http.StatusPaymentRequired:
+		return ErrorKindQuota
+	case status == http.StatusTooManyRequests:
+		if quota && !strings.Contains(lower, "per minute") {
+			return ErrorKindQuota
+		}
+		return ErrorKindRateLimit
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrorKindTimeout
+	case status >= 500:
+		return ErrorKindServer
+	case status >= 400:
+		if quota {
+			return ErrorKindQuota
+		}
+		return ErrorKindBadRequest
+	}
+	return ErrorKindUnknown
+}
+
+// classifyTransportError 分類 client.Do 的錯誤；呼叫端主動取消時原樣回傳（不重試）
+func classifyTransportError(ctx context.Context, provider string, err error) error {
+	if ctx.Err() == context.Canceled {
+		return err
+	}
+	kind := ErrorKindServer
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		kind = ErrorKindTimeout
+	}
+	return &APIError{Provider: provider, Kind: kind, Err: err}
+}
+
+// parseRetryAfter 解析 Retry-After（秒數或 HTTP 日期），無法解析時回傳 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+const maxLoggedBodyBytes = 1024
+
+// secretPattern 常見的 API 金鑰格式（OpenAI sk-、Anthropic sk-ant-、Google AIza）
+var secretPattern = regexp.MustCompile(`(sk-[A-Za-z0-9_\-]{8,}|AIza[0-9A-Za-z_\-]{20,})`)
+
+// redact 遮蔽回應內容中的金鑰（含本次請求 header 中的值）並截斷過長內容，供記錄使用
+func redact(body string, headers map[string]string) string {
+	for _, v := range headers {
+		secret := strings.TrimPrefix(v, "Bearer ")
+		if len(secret) >= 8 {
+			body = strings.ReplaceAll(body, secret, "[REDACTED]")
+		}
+	}
+	body = secretPattern.ReplaceAllString(body, "[REDACTED]")
+	if len(body) > maxLoggedBodyBytes {
+		body = body[:maxLoggedBodyBytes] + "...(truncated)"
+	}
+	return body
+}
@@ -0,0 +1,145 @@
+package imageai
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   ErrorKind
+	}{
+		{http.StatusTooManyRequests, `{"error":{"message":"Rate limit reached"}}`, ErrorKindRateLimit},
+		{http.StatusTooManyRequests, `{"error":{"code":"insufficient_quota"}}`, ErrorKindQuota},
+		{http.StatusTooManyRequests, `{"error":{"message":"Quota exceeded for requests per minute"}}`, ErrorKindRateLimit},
+		{http.StatusPaymentRequired, "", ErrorKindQuota},
+		{http.StatusBadRequest, `{"error":{"message":"Your credit balance is too low"}}`, ErrorKindQuota},
+		{http.StatusBadRequest, `{"error":{"message":"invalid image"}}`, ErrorKindBadRequest},
+		{http.StatusUnauthorized, "", ErrorKindBadRequest},
+		{http.StatusRequestTimeout, "", ErrorKindTimeout},
+		{http.StatusGatewayTimeout, "", ErrorKindTimeout},
+		{http.StatusInternalServerError, "", ErrorKindServer},
+		{529, `{"type":"overloaded_error"}`, ErrorKindServer},
+		{http.StatusFound, "", ErrorKindUnknown},
+	}
+	for _, tt := range tests {
+		if got := classifyStatus(tt.status, tt.body); got != tt.want {
+			t.Errorf("classifyStatus(%d, %q) = %s, want %s", tt.status, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{" 120 ", 2 * time.Minute},
+		{"0", 0},
+		{"-3", 0},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-30 * time.Second).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	for attempt := 1; attempt <= 6; attempt++ {
+		base := min(baseBackoff<<(attempt-1), maxBackoff)
+		for i := 0; i < 200; i++ {
+			wait, ok := backoff(attempt, 0)
+			if !ok {
+				t.Fatalf("attempt %d: backoff without Retry-After should retry", attempt)
+			}
+			if wait < base/2 || wait >= base/2+base {
+				t.Fatalf("attempt %d: wait %s outside [%s, %s)", attempt, wait, base/2, base/2+base)
+			}
+		}
+	}
+}
+
+func TestBackoffRetryAfter(t *testing.T) {
+	tests := []struct {
+		retryAfter time.Duration
+		wantWait   time.Duration
+		wantRetry  bool
+	}{
+		{2 * time.Second, 2 * time.Second, true},
+		{maxBackoff, maxBackoff, true},
+		{maxBackoff + time.Second, maxBackoff + time.Second, false},
+		{time.Hour, time.Hour, false},
+	}
+	for _, tt := range tests {
+		wait, ok := backoff(1, tt.retryAfter)
+		if wait != tt.wantWait || ok != tt.wantRetry {
+			t.Errorf("backoff(1, %s) = (%s, %v), want (%s, %v)", tt.retryAfter, wait, ok, tt.wantWait, tt.wantRetry)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	headers := map[string]string{
+		"Authorization":  "Bearer header-secret-123",
+		"x-goog-api-key": "short",
+	}
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		notWant []string
+	}{
+		{
+			name:    "header secret",
+			body:    `{"error":"invalid key header-secret-123"}`,
+			want:    `{"error":"invalid key [REDACTED]"}`,
+			notWant: []string{"header-secret-123"},
+		},
+		{
+			name:    "openai and anthropic keys",
+			body:    "keys sk-abcdefgh12345 and sk-ant-api03-XYZxyz_987",
+			want:    "keys [REDACTED] and [REDACTED]",
+			notWant: []string{"sk-abc", "sk-ant"},
+		},
+		{
+			name:    "google key",
+			body:    "API key AIzaSyA1234567890abcdefghijk not valid",
+			want:    "API key [REDACTED] not valid",
+			notWant: []string{"AIza"},
+		},
+		{
+			name: "short header values are kept",
+			body: "short response",
+			want: "short response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redact(tt.body, headers)
+			if got != tt.want {
+				t.Errorf("redact = %q, want %q", got, tt.want)
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("redact kept %q: %q", s, got)
+				}
+			}
+		})
+	}
+
+	long := redact(strings.Repeat("x", maxLoggedBodyBytes+10), nil)
+	if !strings.HasSuffix(long, "...(truncated)") || len(long) != maxLoggedBodyBytes+len("...(truncated)") {
+		t.Errorf("long body not truncated: len=%d", len(long))
+	}
+}
@@ -0,0 +1,151 @@
+package imageai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// EXIF Orientation 值（1 為正常方向），參考 TIFF/EXIF 規格 tag 0x0112
+const (
+	orientationNormal        = 1
+	orientationFlipH         = 2
+	orientationRotate180     = 3
+	orientationFlipV         = 4
+	orientationTranspose     = 5
+	orientationRotate90CW    = 6
+	orientationTransverse    = 7
+	orientationRotate90CCW   = 8
+	exifOrientationTag       = 0x0112
+	jpegMarkerAPP1           = 0xE1
+	jpegMarkerStartOfScan    = 0xDA
+	maxExifIFDEntriesToCheck = 512
+)
+
+// exifOrientation 從 JPEG（APP1 區段）或 WebP（EXIF chunk）中讀取 Orientation，找不到或格式不符時回傳 1
+func exifOrientation(data []byte) int {
+	switch {
+	case len(data) > 2 && data[0] == 0xFF && data[1] == 0xD8:
+		return jpegOrientation(data)
+	case len(data) > 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return webpOrientation(data)
+	}
+	return orientationNormal
+}
+
+// jpegOrientation 逐一走訪 JPEG 區段，直到影像資料（SOS）為止
+func jpegOrientation(data []byte) int {
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return orientationNormal
+		}
+		marker := data[pos+1]
+		if marker == jpegMarkerStartOfScan {
+			break
+		}
+		size := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if size < 2 || pos+2+size > len(data) {
+			break
+		}
+		segment := data[pos+4 : pos+2+size]
+		if marker == jpegMarkerAPP1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + size
+	}
+	return orientationNormal
+}
+
+// webpOrientation 在 RIFF chunk 中找 EXIF chunk（內容為 TIFF 結構，部分編碼器會保留 Exif 前綴）
+func webpOrientation(data []byte) int {
+	pos := 12
+	for pos+8 <= len(data) {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		if size < 0 || pos+8+size > len(data) {
+			break
+		}
+		if id == "EXIF" {
+			payload := bytes.TrimPrefix(data[pos+8:pos+8+size], []byte("Exif\x00\x00"))
+			return tiffOrientation(payload)
+		}
+		pos += 8 + size + size%2 // chunk 以偶數長度對齊
+	}
+	return orientationNormal
+}
+
+// tiffOrientation 解析 TIFF 標頭與 IFD0，回傳 Orientation tag 的值
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return orientationNormal
+	}
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientationNormal
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return orientationNormal
+	}
+	count := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count && i < maxExifIFDEntriesToCheck; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:entry+2]) != exifOrientationTag {
+			continue
+		}
+		// 型別為 SHORT，值直接存放在 value offset 欄位的前 2 bytes
+		value := int(order.Uint16(tiff[entry+8 : entry+10]))
+		if value >= orientationNormal && value <= orientationRotate90CCW {
+			return value
+		}
+		break
+	}
+	return orientationNormal
+}
+
+// applyOrientation 依 EXIF Orientation 將影像轉正（翻轉 / 旋轉），方向正常時直接回傳原圖
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= orientationNormal || orientation > orientationRotate90CCW {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	swap := orientation >= orientationTranspose
+	dstW, dstH := w, h
+	if swap {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case orientationFlipH:
+				dx, dy = w-1-x, y
+			case orientationRotate180:
+				dx, dy = w-1-x, h-1-y
+			case orientationFlipV:
+				dx, dy = x, h-1-y
+			case orientationTranspose:
+				dx, dy = y, x
+			case orientationRotate90CW:
+				dx, dy = h-1-y, x
+			case orientationTransverse:
+				dx, dy = h-1-y, w-1-x
+			case orientationRotate90CCW:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
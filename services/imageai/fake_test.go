@@ -0,0 +1,74 @@
+package imageai
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFakeRecognizerText(t *testing.T) {
+	tests := []struct {
+		text       string
+		wantStatus RecognitionStatus
+		wantNames  []string
+	}{
+		{"白飯、荷包蛋", StatusOK, []string{"白飯", "荷包蛋"}},
+		{"牛肉麵和燙青菜", StatusOK, []string{"牛肉麵", "燙青菜"}},
+		{"apple, banana", StatusOK, []string{"apple", "banana"}},
+		{"", StatusNoFood, nil},
+		{" ，、 ", StatusNoFood, nil},
+	}
+	r := NewFakeRecognizer()
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			result, err := r.RecognizeText(context.Background(), tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", result.Status, tt.wantStatus)
+			}
+			if result.HasFood() != (tt.wantStatus == StatusOK) {
+				t.Errorf("HasFood() = %v for status %q", result.HasFood(), result.Status)
+			}
+			if len(result.Items) != len(tt.wantNames) {
+				t.Fatalf("items = %+v, want %v", result.Items, tt.wantNames)
+			}
+			for i, name := range tt.wantNames {
+				if result.Items[i].Name != name {
+					t.Errorf("item %d = %q, want %q", i, result.Items[i].Name, name)
+				}
+			}
+		})
+	}
+}
+
+func TestFakeRecognizerImage(t *testing.T) {
+	r := NewFakeRecognizer()
+	for name, recognize := range map[string]func(ctx context.Context) (*RecognitionResult, error){
+		"single": func(ctx context.Context) (*RecognitionResult, error) { return r.RecognizeImage(ctx, []byte("jpeg")) },
+		"set": func(ctx context.Context) (*RecognitionResult, error) {
+			return r.RecognizeImages(ctx, [][]byte{[]byte("a"), []byte("b")})
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := recognize(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.HasFood() || len(result.Items) != len(r.Items) {
+				t.Errorf("result = %+v, want %d items with status ok", result, len(r.Items))
+			}
+			// 回傳的結果不可與 FakeRecognizer.Items 共用底層陣列
+			result.Items[0].Name = "changed"
+			if r.Items[0].Name == "changed" {
+				t.Error("result shares Items with the recognizer")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if _, err := recognize(ctx); err == nil {
+				t.Error("canceled context should return an error")
+			}
+		})
+	}
+}
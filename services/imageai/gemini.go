@@ -0,0 +1,140 @@
+package imageai
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	defaultGeminiModel   = "gemini-2.0-flash"
+)
+
+// GeminiRecognizer 使用 Gemini generateContent API（POST {baseURL}/models/{model}:generateContent）辨識食物
+type GeminiRecognizer struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewGeminiRecognizer 建立 Gemini 辨識；baseURL、model 為空時使用預設值
+func NewGeminiRecognizer(baseURL, apiKey, model string) *GeminiRecognizer {
+	if baseURL == "" {
+		baseURL = defaultGeminiBaseURL
+	}
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	return &GeminiRecognizer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: defaultRequestTimeout},
+	}
+}
+
+// RecognizeImage 辨識圖片中的食物並估算營養成分（以 responseSchema 強制 JSON 格式）
+func (r *GeminiRecognizer) RecognizeImage(ctx context.Context, jpeg []byte) (*RecognitionResult, error) {
+	return r.RecognizeImages(ctx, [][]byte{jpeg})
+}
+
+// RecognizeImages 多張圖片以多個 inline_data part 合併辨識
+func (r *GeminiRecognizer) RecognizeImages(ctx context.Context, jpegs [][]byte) (*RecognitionResult, error) {
+	parts := []map[string]any{
+		{"text": imagePromptFor(ctx, len(jpegs))},
+	}
+	for _, jpeg := range jpegs {
+		parts = append(parts, map[string]any{"inline_data": map[string]any{
+			"mime_type": "image/jpeg",
+			"data":      base64.StdEncoding.EncodeToString(jpeg),
+		}})
+	}
+	return r.request(ctx, parts)
+}
+
+// RecognizeText 將文字描述拆解成食物並估算營養成分
+func (r *GeminiRecognizer) RecognizeText(ctx context.Context, text string) (*RecognitionResult, error) {
+	return r.request(ctx, []map[string]any{
+		{"text": textPromptFor(ctx) + text},
+	})
+}
+
+func (r *GeminiRecognizer) request(ctx context.Context, parts []map[string]any) (*RecognitionResult, error) {
+	body := map[string]any{
+		"contents": []map[string]any{
+			{"role": "user", "parts": parts},
+		},
+		"generationConfig": map[string]any{
+			"responseMimeType": "application/json",
+			"responseSchema":   geminiSchema(nutritionSchema),
+		},
+	}
+
+	var result struct {
+		ModelVersion  string `json:"modelVersion"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+	}
+	url := fmt.Sprintf("%s/models/%s:generateContent", r.baseURL, r.model)
+	headers := map[string]string{"x-goog-api-key": r.apiKey}
+	if err := postJSON(ctx, r.client, "Gemini", url, headers, body, &result); err != nil {
+		return nil, err
+	}
+	var output strings.Builder
+	if len(result.Candidates) > 0 {
+		for _, part := range result.Candidates[0].Content.Parts {
+			output.WriteString(part.Text)
+		}
+	}
+	if output.Len() == 0 {
+		return nil, fmt.Errorf("Gemini API 未回傳內容")
+	}
+	parsed, err := ParseRecognitionResult(output.String())
+	if err != nil {
+		return nil, err
+	}
+	parsed.Usage = &Usage{
+		Provider:     "gemini",
+		Model:        firstNonEmpty(result.ModelVersion, r.model),
+		InputTokens:  result.UsageMetadata.PromptTokenCount,
+		OutputTokens: result.UsageMetadata.CandidatesTokenCount,
+	}
+	return parsed, nil
+}
+
+// geminiSchema 將 JSON Schema 轉為 Gemini 支援的 OpenAPI 子集：type 改為大寫、移除 additionalProperties
+func geminiSchema(schema map[string]any) map[string]any {
+	out := make(map[string]any, len(schema))
+	for k, v := range schema {
+		switch k {
+		case "additionalProperties":
+			continue
+		case "type":
+			out[k] = strings.ToUpper(fmt.Sprint(v))
+		case "properties":
+			props := make(map[string]any)
+			for name, prop := range v.(map[string]any) {
+				props[name] = geminiSchema(prop.(map[string]any))
+			}
+			out[k] = props
+		case "items":
+			out[k] = geminiSchema(v.(map[string]any))
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package imageai
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"sync"
+)
+
+// ErrHEICUnsupported 收到 HEIC / HEIF 圖片但尚未註冊解碼器
+var ErrHEICUnsupported = errors.New("HEIC 圖片需先註冊解碼器（RegisterHEICDecoder）")
+
+// heicBrands ISO-BMFF ftyp box 中代表 HEIC / HEIF 的 major brand
+var heicBrands = []string{"heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1"}
+
+var heicOnce sync.Once
+
+// RegisterHEICDecoder 註冊 HEIC / HEIF 解碼器（例如以 libheif 或 WASM 實作的套件），註冊後 Resize 即可處理 iPhone 原始照片。
+// 標準庫與 golang.org/x/image 沒有 HEIC 解碼，因此以外掛方式提供；只有第一次呼叫生效。
+func RegisterHEICDecoder(decode func(io.Reader) (image.Image, error), decodeConfig func(io.Reader) (image.Config, error)) {
+	heicOnce.Do(func() {
+		for _, brand := range heicBrands {
+			// 前 4 bytes 為 box 長度，以 ? 萬用字元略過
+			image.RegisterFormat("heic", "????ftyp"+brand, decode, decodeConfig)
+		}
+	})
+}
+
+// isHEIC 依 ftyp box 判斷是否為 HEIC / HEIF
+func isHEIC(data []byte) bool {
+	if len(data) < 12 || !bytes.Equal(data[4:8], []byte("ftyp")) {
+		return false
+	}
+	brand := string(data[8:12])
+	for _, b := range heicBrands {
+		if brand == b {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package imageai
+
+import (
+	"context"
+
+	"project/services/i18n"
+)
+
+type localeKey struct{}
+
+// WithLocale 指定這次辨識使用的語系（提示詞與回傳的食物名稱語言），未指定時使用 i18n.DefaultLocale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFrom 取得 ctx 指定的語系
+func LocaleFrom(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok && i18n.Supported(locale) {
+		return locale
+	}
+	return i18n.DefaultLocale
+}
+
+// imagePromptFor 依語系與圖片張數組出提示詞（多張時前面加上合併辨識的說明）
+func imagePromptFor(ctx context.Context, n int) string {
+	locale := LocaleFrom(ctx)
+	prompt := i18n.T(locale, "prompt.image")
+	if n > 1 {
+		prompt = i18n.T(locale, "prompt.multi_image", n) + prompt
+	}
+	return prompt
+}
+
+// textPromptFor 文字描述（語音轉文字）的提示詞，後面接使用者描述
+func textPromptFor(ctx context.Context) string {
+	return i18n.T(LocaleFrom(ctx), "prompt.text")
+}
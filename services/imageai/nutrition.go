@@ -0,0 +1,254 @@
+package imageai
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// FoodItem 單項食物的辨識與營養估算結果（營養素單位為公克）
+type FoodItem struct {
+	Name       string  `json:"name"`
+	Portion    string  `json:"portion"`
+	Kcal       float64 `json:"kcal"`
+	Protein    float64 `json:"protein"`
+	Fat        float64 `json:"fat"`
+	Carbs      float64 `json:"carbs"`
+	Confidence float64 `json:"confidence"` // 0 ~ 1
+
+	// Alternatives 信心偏低時模型認為可能的其他食物（同份量的營養估算），供使用者選擇
+	Alternatives []Alternative `json:"alternatives"`
+}
+
+// Alternative 低信心食物的候選答案
+type Alternative struct {
+	Name    string  `json:"name"`
+	Kcal    float64 `json:"kcal"`
+	Protein float64 `json:"protein"`
+	Fat     float64 `json:"fat"`
+	Carbs   float64 `json:"carbs"`
+}
+
+// RecognitionStatus 辨識結果的整體狀態
+type RecognitionStatus string
+
+const (
+	StatusOK      RecognitionStatus = "ok"      // 辨識出食物
+	StatusNoFood  RecognitionStatus = "no_food" // 圖片 / 描述中沒有食物
+	StatusUnclear RecognitionStatus = "unclear" // 圖片模糊、過暗或角度不佳，無法判斷
+)
+
+// LowConfidenceThreshold 信心低於此值且有候選答案時，先詢問使用者而不直接採用
+const LowConfidenceThreshold = 0.6
+
+// maxAlternatives 每項食物最多保留的候選答案數（加上原答案作為快速回覆按鈕）
+const maxAlternatives = 3
+
+// RecognitionResult 一次辨識的結構化結果；Status 為 ok 時 Items 至少一項
+type RecognitionResult struct {
+	Status   RecognitionStatus `json:"status"`
+	Items    []FoodItem        `json:"items"`
+	CacheHit bool              `json:"-"` // 結果是否來自辨識快取（未呼叫模型）
+	Usage    *Usage            `json:"-"` // 本次呼叫模型的 token 用量（快取命中或未回傳時為 nil）
+}
+
+// Clone 複製結果（含 Items 與候選答案），避免快取中的資料被呼叫端修改；不含 Usage（快取命中不應重複計費）
+func (r *RecognitionResult) Clone() *RecognitionResult {
+	if r == nil {
+		return nil
+	}
+	items := make([]FoodItem, len(r.Items))
+	for i, item := range r.Items {
+		item.Alternatives = append([]Alternative(nil), item.Alternatives...)
+		items[i] = item
+	}
+	return &RecognitionResult{Status: r.Status, Items: items, CacheHit: r.CacheHit}
+}
+
+// nutritionSchema Responses API 的 JSON Schema（strict 模式：所有欄位必填、不允許額外欄位）
+var nutritionSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"items": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":       map[string]any{"type": "string", "description": "食物名稱（使用提示詞指定的語言）"},
+					"portion":    map[string]any{"type": "string", "description": "估計份量，例如「1碗（約200g）」"},
+					"kcal":       map[string]any{"type": "number", "description": "估計熱量（大卡）"},
+					"protein":    map[string]any{"type": "number", "description": "蛋白質（公克）"},
+					"fat":        map[string]any{"type": "number", "description": "脂肪（公克）"},
+					"carbs":      map[string]any{"type": "number", "description": "碳水化合物（公克）"},
+					"confidence": map[string]any{"type": "number", "description": "辨識信心 0 ~ 1"},
+					"alternatives": map[string]any{
+						"type":        "array",
+						"description": "信心低於 0.6 時其他可能的食物（最多 3 項），否則為空陣列",
+						"items": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"name":    map[string]any{"type": "string"},
+								"kcal":    map[string]any{"type": "number"},
+								"protein": map[string]any{"type": "number"},
+								"fat":     map[string]any{"type": "number"},
+								"carbs":   map[string]any{"type": "number"},
+							},
+							"required":             []string{"name", "kcal", "protein", "fat", "carbs"},
+							"additionalProperties": false,
+						},
+					},
+				},
+				"required":             []string{"name", "portion", "kcal", "protein", "fat", "carbs", "confidence", "alternatives"},
+				"additionalProperties": false,
+			},
+		},
+		"status": map[string]any{
+			"type":        "string",
+			"enum":        []string{string(StatusOK), string(StatusNoFood), string(StatusUnclear)},
+			"description": "ok：有食物；no_food：沒有食物；unclear：無法判斷",
+		},
+	},
+	"required":             []string{"status", "items"},
+	"additionalProperties": false,
+}
+
+// nutritionTextFormat Responses API 的 text.format 設定，強制模型輸出符合 nutritionSchema 的 JSON
+func nutritionTextFormat() map[string]any {
+	return map[string]any{
+		"format": map[string]any{
+			"type":   "json_schema",
+			"name":   "food_nutrition",
+			"schema": nutritionSchema,
+			"strict": true,
+		},
+	}
+}
+
+// ParseRecognitionResult 解析並驗證模型輸出的 JSON，並校正 Status 與 Items 不一致的情況
+func ParseRecognitionResult(content string) (*RecognitionResult, error) {
+	var result RecognitionResult
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &result); err != nil {
+		return nil, fmt.Errorf("辨識結果不是合法 JSON: %w", err)
+	}
+	if err := result.Validate(); err != nil {
+		return nil, err
+	}
+	result.normalize()
+	return &result, nil
+}
+
+// normalize 未回傳 status 時依 Items 推斷；ok 但沒有食物視為 no_food；no_food / unclear 一律清空 Items
+func (r *RecognitionResult) normalize() {
+	switch {
+	case r.Status == "" && len(r.Items) > 0:
+		r.Status = StatusOK
+	case r.Status == "" || (r.Status == StatusOK && len(r.Items) == 0):
+		r.Status = StatusNoFood
+	}
+	if r.Status != StatusOK {
+		r.Items = nil
+	}
+	for i := range r.Items {
+		if len(r.Items[i].Alternatives) > maxAlternatives {
+			r.Items[i].Alternatives = r.Items[i].Alternatives[:maxAlternatives]
+		}
+	}
+}
+
+// Validate 檢查狀態值與每一項食物：名稱必填、數值不可為負數或非有限值、信心值介於 0 ~ 1
+func (r *RecognitionResult) Validate() error {
+	switch r.Status {
+	case "", StatusOK, StatusNoFood, StatusUnclear:
+	default:
+		return fmt.Errorf("未知的辨識狀態: %s", r.Status)
+	}
+	for i, item := range r.Items {
+		if strings.TrimSpace(item.Name) == "" {
+			return fmt.Errorf("第 %d 項食物缺少名稱", i+1)
+		}
+		for field, v := range map[string]float64{
+			"kcal": item.Kcal, "protein": item.Protein, "fat": item.Fat, "carbs": item.Carbs,
+		} {
+			if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+				return fmt.Errorf("第 %d 項食物 %s 數值不合法: %v", i+1, field, v)
+			}
+		}
+		if math.IsNaN(item.Confidence) || item.Confidence < 0 || item.Confidence > 1 {
+			return fmt.Errorf("第 %d 項食物 confidence 需介於 0 ~ 1: %v", i+1, item.Confidence)
+		}
+	}
+	return nil
+}
+
+// HasFood 是否辨識出任何食物
+func (r *RecognitionResult) HasFood() bool {
+	return r != nil && r.Status == StatusOK && len(r.Items) > 0
+}
+
+// NoFood 確定沒有食物
+func (r *RecognitionResult) NoFood() bool {
+	return r == nil || r.Status == StatusNoFood
+}
+
+// Unclear 無法判斷（應請使用者重拍）
+func (r *RecognitionResult) Unclear() bool {
+	return r != nil && r.Status == StatusUnclear
+}
+
+// NeedsClarification 回傳第一個信心偏低且有候選答案的食物索引，沒有時回傳 -1
+func (r *RecognitionResult) NeedsClarification() int {
+	if !r.HasFood() {
+		return -1
+	}
+	for i, item := range r.Items {
+		if item.Confidence < LowConfidenceThreshold && len(item.Alternatives) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Choices 第 index 項食物可供選擇的名稱：原答案在前，其後為候選答案
+func (r *RecognitionResult) Choices(index int) []string {
+	if index < 0 || index >= len(r.Items) {
+		return nil
+	}
+	item := r.Items[index]
+	choices := []string{item.Name}
+	for _, alt := range item.Alternatives {
+		choices = append(choices, alt.Name)
+	}
+	return choices
+}
+
+// Clarify 以使用者的選擇更新第 index 項食物：choice 0 為原答案，1 起為候選答案（採用其營養估算）。
+// 更新後信心設為 1 並清除候選答案，之後不再詢問。
+func (r *RecognitionResult) Clarify(index, choice int) error {
+	if index < 0 || index >= len(r.Items) {
+		return fmt.Errorf("食物索引超出範圍: %d", index)
+	}
+	item := &r.Items[index]
+	if choice < 0 || choice > len(item.Alternatives) {
+		return fmt.Errorf("選項超出範圍: %d", choice)
+	}
+	if choice > 0 {
+		alt := item.Alternatives[choice-1]
+		item.Name, item.Kcal, item.Protein, item.Fat, item.Carbs = alt.Name, alt.Kcal, alt.Protein, alt.Fat, alt.Carbs
+	}
+	item.Confidence = 1
+	item.Alternatives = nil
+	return nil
+}
+
+// Total 加總所有食物的熱量與三大營養素
+func (r *RecognitionResult) Total() FoodItem {
+	var total FoodItem
+	for _, item := range r.Items {
+		total.Kcal += item.Kcal
+		total.Protein += item.Protein
+		total.Fat += item.Fat
+		total.Carbs += item.Carbs
+	}
+	return total
+}
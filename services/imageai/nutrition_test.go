@@ -0,0 +1,86 @@
+package imageai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRecognitionResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantErr    string
+		wantStatus RecognitionStatus
+		wantItems  int
+	}{
+		{
+			name:       "ok",
+			content:    `{"status":"ok","items":[{"name":"白飯","portion":"1碗","kcal":280,"protein":5,"fat":0.5,"carbs":62,"confidence":0.9,"alternatives":[]}]}`,
+			wantStatus: StatusOK,
+			wantItems:  1,
+		},
+		{
+			name:       "surrounding whitespace",
+			content:    "\n  {\"status\":\"no_food\",\"items\":[]}  \n",
+			wantStatus: StatusNoFood,
+		},
+		{
+			name:       "missing status with items",
+			content:    `{"items":[{"name":"荷包蛋","kcal":90,"confidence":0.8}]}`,
+			wantStatus: StatusOK,
+			wantItems:  1,
+		},
+		{
+			name:       "missing status without items",
+			content:    `{"items":[]}`,
+			wantStatus: StatusNoFood,
+		},
+		{
+			name:       "ok without items",
+			content:    `{"status":"ok","items":[]}`,
+			wantStatus: StatusNoFood,
+		},
+		{
+			name:       "unclear drops items",
+			content:    `{"status":"unclear","items":[{"name":"白飯","kcal":280,"confidence":0.2}]}`,
+			wantStatus: StatusUnclear,
+		},
+		{name: "not json", content: "白飯 280 kcal", wantErr: "不是合法 JSON"},
+		{name: "unknown status", content: `{"status":"maybe","items":[]}`, wantErr: "未知的辨識狀態"},
+		{name: "missing name", content: `{"status":"ok","items":[{"name":" ","kcal":1,"confidence":0.5}]}`, wantErr: "缺少名稱"},
+		{name: "negative kcal", content: `{"status":"ok","items":[{"name":"白飯","kcal":-1,"confidence":0.5}]}`, wantErr: "kcal"},
+		{name: "confidence above 1", content: `{"status":"ok","items":[{"name":"白飯","kcal":1,"confidence":1.5}]}`, wantErr: "confidence"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseRecognitionResult(tt.content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != tt.wantStatus || len(result.Items) != tt.wantItems {
+				t.Errorf("status=%q items=%d, want status=%q items=%d", result.Status, len(result.Items), tt.wantStatus, tt.wantItems)
+			}
+		})
+	}
+}
+
+func TestParseRecognitionResultTrimsAlternatives(t *testing.T) {
+	content := `{"status":"ok","items":[{"name":"滷肉飯","kcal":500,"confidence":0.4,"alternatives":[
+		{"name":"肉燥飯","kcal":480},{"name":"控肉飯","kcal":650},{"name":"雞肉飯","kcal":400},{"name":"豬油拌飯","kcal":450}]}]}`
+	result, err := ParseRecognitionResult(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(result.Items[0].Alternatives); got != maxAlternatives {
+		t.Errorf("alternatives = %d, want %d", got, maxAlternatives)
+	}
+	if result.NeedsClarification() != 0 {
+		t.Errorf("NeedsClarification() = %d, want 0", result.NeedsClarification())
+	}
+}
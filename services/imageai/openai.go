@@ -1,87 +1,111 @@
 package imageai
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
-	"time"
+	"strings"
 )
 
 const (
-	defaultModel   = "gpt-4o-mini"
-	promptTemplate = `請辨識這張圖片中的食物，回覆格式規定如下：
-- 若有食物：只回傳食物名稱，以頓號或逗號分隔，例如「白飯、炒蛋、青菜」。
-- 若無食物：只回傳「無食物」。
-不要加任何說明、標點以外的多餘文字。`
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
 )
 
-// RecognizeFood 使用 OpenAI Responses API 辨識圖片中的食物。
-// base64Image 為 JPEG base64 編碼，回傳精簡食物文字與是否成功。
-func RecognizeFood(ctx context.Context, base64Image string) (foods string, success bool, err error) {
-	token := os.Getenv("OPEN_AI_TOKEN")
-	if token == "" {
-		return "", false, fmt.Errorf("OPEN_AI_TOKEN 未設定")
+// OpenAIRecognizer 使用 OpenAI Responses API（POST {baseURL}/responses）辨識食物
+type OpenAIRecognizer struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+// NewOpenAIRecognizer 建立 OpenAI 辨識；baseURL、model 為空時使用預設值
+func NewOpenAIRecognizer(baseURL, apiKey, model string) *OpenAIRecognizer {
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
 	}
-	model := os.Getenv("OPENAI_IMAGE_MODEL")
 	if model == "" {
-		model = defaultModel
-	}
-
-	// Responses API 格式：input 為 user message，content 含 input_text 與 input_image
-	body := map[string]any{
-		"model": model,
-		"input": []map[string]any{
-			{
-				"role": "user",
-				"content": []map[string]any{
-					{"type": "input_text", "text": promptTemplate},
-					{
-						"type":      "input_image",
-						"image_url": fmt.Sprintf("data:image/jpeg;base64,%s", base64Image),
-					},
-				},
-			},
-		},
+		model = defaultOpenAIModel
 	}
-	data, err := json.Marshal(body)
-	if err != nil {
-		return "", false, err
+	return &OpenAIRecognizer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: defaultRequestTimeout},
 	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.openai.com/v1/responses", bytes.NewReader(data))
-	if err != nil {
-		return "", false, err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
+// RecognizeImage 辨識圖片中的食物並估算營養成分；輸出以 JSON Schema 強制格式，並在 Go 端驗證後回傳結構化結果。
+func (r *OpenAIRecognizer) RecognizeImage(ctx context.Context, jpeg []byte) (*RecognitionResult, error) {
+	return r.RecognizeImages(ctx, [][]byte{jpeg})
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", false, err
+// RecognizeImages 多張圖片放在同一則 user message 中合併辨識
+func (r *OpenAIRecognizer) RecognizeImages(ctx context.Context, jpegs [][]byte) (*RecognitionResult, error) {
+	content := []map[string]any{
+		{"type": "input_text", "text": imagePromptFor(ctx, len(jpegs))},
 	}
-	defer resp.Body.Close()
+	for _, jpeg := range jpegs {
+		content = append(content, map[string]any{
+			"type":      "input_image",
+			"image_url": fmt.Sprintf("data:image/jpeg;base64,%s", base64.StdEncoding.EncodeToString(jpeg)),
+		})
+	}
+	return r.request(ctx, content)
+}
+
+// RecognizeText 將文字描述拆解成食物並估算營養成分，回傳格式同 RecognizeImage。
+func (r *OpenAIRecognizer) RecognizeText(ctx context.Context, text string) (*RecognitionResult, error) {
+	return r.request(ctx, []map[string]any{
+		{"type": "input_text", "text": textPromptFor(ctx) + text},
+	})
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", false, fmt.Errorf("OpenAI API 回傳 %d", resp.StatusCode)
+// request 呼叫 Responses API（content 為 user message 的內容），解析並驗證營養估算 JSON。
+func (r *OpenAIRecognizer) request(ctx context.Context, content []map[string]any) (*RecognitionResult, error) {
+	// Responses API 格式：input 為 user message，content 含 input_text 與 input_image
+	body := map[string]any{
+		"model": r.model,
+		"input": []map[string]any{
+			{"role": "user", "content": content},
+		},
+		"text": nutritionTextFormat(),
 	}
 
 	var result struct {
+		Model      string            `json:"model"`
 		OutputText string            `json:"output_text"`
 		Output     []json.RawMessage `json:"output"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + r.apiKey}
+	if err := postJSON(ctx, r.client, "OpenAI", r.baseURL+"/responses", headers, body, &result); err != nil {
+		return nil, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", false, err
+	output := result.OutputText
+	if output == "" && len(result.Output) > 0 {
+		output = extractTextFromOutput(result.Output)
 	}
-	content := result.OutputText
-	if content == "" && len(result.Output) > 0 {
-		content = extractTextFromOutput(result.Output)
+	if output == "" {
+		return nil, fmt.Errorf("OpenAI API 未回傳內容")
+	}
+	parsed, err := ParseRecognitionResult(output)
+	if err != nil {
+		return nil, err
 	}
-	return content, content != "", nil
+	parsed.Usage = &Usage{
+		Provider:     "openai",
+		Model:        firstNonEmpty(result.Model, r.model),
+		InputTokens:  result.Usage.InputTokens,
+		OutputTokens: result.Usage.OutputTokens,
+	}
+	return parsed, nil
 }
 
 // extractTextFromOutput 從 Responses API 的 output 陣列取出文字（content 可能為 string 或 array）。
@@ -120,9 +144,3 @@ func extractTextFromOutput(output []json.RawMessage) string {
 	}
 	return ""
 }
-
-// RecognizeFoodFromBytes 從 JPEG 位元組辨識食物，內部轉 base64 後呼叫 RecognizeFood。
-func RecognizeFoodFromBytes(ctx context.Context, imgBytes []byte) (foods string, success bool, err error) {
-	b64 := base64.StdEncoding.EncodeToString(imgBytes)
-	return RecognizeFood(ctx, b64)
-}
@@ -0,0 +1,172 @@
+package imageai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	logsvc "project/services/log"
+)
+
+const (
+	defaultRequestTimeout = 30 * time.Second
+
+	// 重試設定：最多呼叫 maxAttempts 次，退避由 baseBackoff 起倍增至 maxBackoff
+	maxAttempts = 3
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 8 * time.Second
+)
+
+// Recognizer 食物辨識與營養估算的供應商介面
+type Recognizer interface {
+	// RecognizeImage 辨識 JPEG 圖片中的食物並估算營養
+	RecognizeImage(ctx context.Context, jpeg []byte) (*RecognitionResult, error)
+	// RecognizeImages 將同一餐的多張 JPEG 圖片以一次請求合併辨識
+	RecognizeImages(ctx context.Context, jpegs [][]byte) (*RecognitionResult, error)
+	// RecognizeText 將文字描述（例如語音轉出的文字）拆解成食物並估算營養
+	RecognizeText(ctx context.Context, text string) (*RecognitionResult, error)
+}
+
+// ProviderConfig 供應商設定
+type ProviderConfig struct {
+	Provider string // openai / gemini / anthropic / fake
+	BaseURL  string // 空值使用各供應商的官方端點
+	Model    string // 空值使用各供應商的預設模型
+	APIKey   string
+}
+
+// ProviderConfigFromEnv 從環境變數讀取：VISION_PROVIDER（預設 openai）、VISION_BASE_URL、VISION_MODEL、VISION_API_KEY。
+// openai 為了相容舊設定，未設定時沿用 OPEN_AI_TOKEN 與 OPENAI_IMAGE_MODEL。
+func ProviderConfigFromEnv() ProviderConfig {
+	cfg := ProviderConfig{
+		Provider: strings.ToLower(os.Getenv("VISION_PROVIDER")),
+		BaseURL:  os.Getenv("VISION_BASE_URL"),
+		Model:    os.Getenv("VISION_MODEL"),
+		APIKey:   os.Getenv("VISION_API_KEY"),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = "openai"
+	}
+	if cfg.Provider == "openai" {
+		if cfg.APIKey == "" {
+			cfg.APIKey = os.Getenv("OPEN_AI_TOKEN")
+		}
+		if cfg.Model == "" {
+			cfg.Model = os.Getenv("OPENAI_IMAGE_MODEL")
+		}
+	}
+	return cfg
+}
+
+// NewRecognizer 依設定建立對應的供應商
+func NewRecognizer(cfg ProviderConfig) (Recognizer, error) {
+	if cfg.Provider != "fake" && cfg.APIKey == "" {
+		return nil, fmt.Errorf("%s 辨識服務未設定 API Key", cfg.Provider)
+	}
+	switch cfg.Provider {
+	case "openai":
+		return NewOpenAIRecognizer(cfg.BaseURL, cfg.APIKey, cfg.Model), nil
+	case "gemini":
+		return NewGeminiRecognizer(cfg.BaseURL, cfg.APIKey, cfg.Model), nil
+	case "anthropic":
+		return NewAnthropicRecognizer(cfg.BaseURL, cfg.APIKey, cfg.Model), nil
+	case "fake":
+		return NewFakeRecognizer(), nil
+	default:
+		return nil, fmt.Errorf("不支援的 VISION_PROVIDER: %s", cfg.Provider)
+	}
+}
+
+// NewRecognizerFromEnv 依環境變數建立辨識供應商
+func NewRecognizerFromEnv() (Recognizer, error) {
+	return NewRecognizer(ProviderConfigFromEnv())
+}
+
+// postJSON 送出 JSON POST 請求並將 200 回應解碼至 out。
+// 限流、逾時與 5xx 會以帶抖動的指數退避重試（有 Retry-After 時依其等待），
+// Retry-After 超過 maxBackoff 或等待時間超過 ctx 期限則不再重試；
+// 失敗時回傳 *APIError，回應內容遮蔽金鑰後記錄於 log。
+func postJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := doPostJSON(ctx, client, provider, url, headers, data, out)
+		if err == nil {
+			return nil
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.Retryable() || attempt >= maxAttempts {
+			return err
+		}
+		wait, ok := backoff(attempt, apiErr.RetryAfter)
+		if !ok {
+			logsvc.Warn("%s API 要求 %s 後再試，超過重試等待上限，不再重試", provider, apiErr.RetryAfter)
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		logsvc.Warn("%s API 第 %d 次呼叫失敗（%s），%s 後重試", provider, attempt, apiErr.Kind, wait)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// doPostJSON 送出單次請求
+func doPostJSON(ctx context.Context, client *http.Client, provider, url string, headers map[string]string, data []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return classifyTransportError(ctx, provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		apiErr := &APIError{
+			Provider:   provider,
+			Kind:       classifyStatus(resp.StatusCode, string(raw)),
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			Body:       redact(string(raw), headers),
+		}
+		logsvc.Error("%s API 回傳 %d kind=%s body=%s", provider, resp.StatusCode, apiErr.Kind, apiErr.Body)
+		return apiErr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// backoff 第 attempt 次失敗後的等待時間：有 Retry-After 時完全依其等待，超過 maxBackoff 則回傳 false（不重試，
+// 提早重試只會再被限流）；否則為 baseBackoff*2^(attempt-1) 並加上 ±50% 抖動，避免多個請求同時重試
+func backoff(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= maxBackoff
+	}
+	wait := baseBackoff << (attempt - 1)
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int64N(int64(wait))), true
+}
@@ -2,11 +2,16 @@ package imageai
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"image"
+	_ "image/gif" // GIF 只解碼第一格
 	"image/jpeg"
+	_ "image/png"
 	"io"
 
 	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
 )
 
 const (
@@ -15,11 +20,15 @@ const (
 	JpegQuality  = 85
 )
 
-// Resize 將圖片縮放為短邊 768px、長邊不超過 2000px，維持比例。
-// 支援 JPEG、PNG 解碼；輸出為 JPEG（品質 85%）。
+// Resize 將圖片縮放為短邊 768px、長邊不超過 2000px，維持比例，並依 EXIF Orientation 轉正。
+// 支援 JPEG、PNG、WebP、GIF（第一格）解碼，HEIC 需先以 RegisterHEICDecoder 註冊；輸出為 JPEG（品質 85%）。
 // 若原始尺寸已符合，直接縮放或回傳編碼結果。
 func Resize(r io.Reader) ([]byte, string, error) {
-	img, _, err := image.Decode(r)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	img, err := decodeImage(data)
 	if err != nil {
 		return nil, "", err
 	}
@@ -56,12 +65,62 @@ func Resize(r io.Reader) ([]byte, string, error) {
 		newH = 1
 	}
 
+	out, err := scaleToJPEG(img, newW, newH, exifOrientation(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return out, "image/jpeg", nil
+}
+
+// ResizeVariants 將圖片依序縮放為長邊不超過 maxSides 各值的 JPEG（不放大、依 EXIF Orientation 轉正），
+// 只解碼一次，供儲存縮圖、顯示用尺寸等多個版本使用。
+func ResizeVariants(data []byte, maxSides ...int) ([][]byte, error) {
+	img, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	orientation := exifOrientation(data)
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	out := make([][]byte, 0, len(maxSides))
+	for _, maxSide := range maxSides {
+		newW, newH := w, h
+		if long := max(w, h); maxSide > 0 && long > maxSide {
+			newW = max(1, w*maxSide/long)
+			newH = max(1, h*maxSide/long)
+		}
+		variant, err := scaleToJPEG(img, newW, newH, orientation)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, variant)
+	}
+	return out, nil
+}
+
+// decodeImage 解碼圖片；HEIC 且未註冊解碼器時回傳 ErrHEICUnsupported
+func decodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) && isHEIC(data) {
+			return nil, ErrHEICUnsupported
+		}
+		return nil, fmt.Errorf("圖片解碼失敗: %w", err)
+	}
+	return img, nil
+}
+
+// scaleToJPEG 縮放為 newW x newH、依 orientation 轉正後編碼為 JPEG
+func scaleToJPEG(img image.Image, newW, newH, orientation int) ([]byte, error) {
 	dst := image.NewRGBA(image.Rect(0, 0, newW, newH))
-	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	// 透明背景（PNG、GIF、WebP）先鋪白色，避免輸出 JPEG 後變成黑底
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+	// 縮放後再轉正，旋轉的像素量較少
+	out := applyOrientation(dst, orientation)
 
 	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: JpegQuality}); err != nil {
-		return nil, "", err
+	if err := jpeg.Encode(&buf, out, &jpeg.Options{Quality: JpegQuality}); err != nil {
+		return nil, err
 	}
-	return buf.Bytes(), "image/jpeg", nil
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,379 @@
+package imageai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// corner 影像的四個角落
+type corner int
+
+const (
+	topLeft corner = iota
+	topRight
+	bottomRight
+	bottomLeft
+)
+
+// markedImage 建立白底、左上角 1/4 為黑色的影像；只有黑白兩色，GIF 與手寫的 WebP 編碼都能無損表示
+func markedImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x < w/2 && y < h/2 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// darkCorner 回傳四個象限中心唯一偏暗的角落；不是恰好一個時測試失敗
+func darkCorner(t *testing.T, img image.Image) corner {
+	t.Helper()
+	b := img.Bounds()
+	points := map[corner]image.Point{
+		topLeft:     {b.Min.X + b.Dx()/4, b.Min.Y + b.Dy()/4},
+		topRight:    {b.Min.X + b.Dx()*3/4, b.Min.Y + b.Dy()/4},
+		bottomRight: {b.Min.X + b.Dx()*3/4, b.Min.Y + b.Dy()*3/4},
+		bottomLeft:  {b.Min.X + b.Dx()/4, b.Min.Y + b.Dy()*3/4},
+	}
+	found, count := topLeft, 0
+	for c, p := range points {
+		if gray := color.GrayModel.Convert(img.At(p.X, p.Y)).(color.Gray); gray.Y < 128 {
+			found = c
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("偏暗的角落數 = %d，預期 1", count)
+	}
+	return found
+}
+
+// exifTIFF 只含 Orientation 一個 tag 的 TIFF 結構（EXIF 內容）
+func exifTIFF(orientation int, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.BigEndian {
+		copy(tiff, "MM")
+	} else {
+		copy(tiff, "II")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)                   // IFD0 位置
+	order.PutUint16(tiff[8:], 1)                   // 1 個 entry
+	order.PutUint16(tiff[10:], exifOrientationTag) // tag
+	order.PutUint16(tiff[12:], 3)                  // SHORT
+	order.PutUint32(tiff[14:], 1)                  // count
+	order.PutUint16(tiff[18:], uint16(orientation))
+	return tiff // 最後 4 bytes 為下一個 IFD 位置（0）
+}
+
+// withJPEGExif 在 JPEG 的 SOI 之後插入帶 Orientation 的 APP1 區段（big-endian TIFF）
+func withJPEGExif(data []byte, orientation int) []byte {
+	payload := append([]byte("Exif\x00\x00"), exifTIFF(orientation, binary.BigEndian)...)
+	segment := []byte{0xFF, jpegMarkerAPP1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	out = append(out, payload...)
+	return append(out, data[2:]...)
+}
+
+// bitWriter VP8L 使用的 LSB-first 位元寫入
+type bitWriter struct {
+	buf []byte
+	n   uint
+}
+
+func (w *bitWriter) write(v uint32, bits uint) {
+	for i := uint(0); i < bits; i++ {
+		if w.n%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if v>>i&1 == 1 {
+			w.buf[len(w.buf)-1] |= 1 << (w.n % 8)
+		}
+		w.n++
+	}
+}
+
+// riffChunk 組出 RIFF chunk（奇數長度補 0）
+func riffChunk(id string, data []byte) []byte {
+	out := append([]byte(id), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(data)))
+	out = append(out, data...)
+	if len(data)%2 == 1 {
+		out = append(out, 0)
+	}
+	return out
+}
+
+// encodeWebP 將只有黑白兩色的影像編碼為無損 WebP（VP8L，不使用 transform 與 color cache，每個色版為 0 / 255 兩個符號的 simple code）；
+// orientation 大於 0 時使用延伸格式（VP8X）並附上 little-endian 的 EXIF chunk
+func encodeWebP(img *image.RGBA, orientation int) []byte {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	var bw bitWriter
+	bw.write(0x2f, 8)
+	bw.write(uint32(w-1), 14)
+	bw.write(uint32(h-1), 14)
+	bw.write(0, 1)           // alpha_is_used
+	bw.write(0, 3)           // version
+	bw.write(0, 1)           // 沒有 transform
+	bw.write(0, 1)           // 沒有 color cache
+	bw.write(0, 1)           // 沒有 meta prefix code
+	for i := 0; i < 3; i++ { // green、red、blue：0 與 255 兩個符號
+		bw.write(1, 1)
+		bw.write(1, 1)
+		bw.write(1, 1)
+		bw.write(0, 8)
+		bw.write(255, 8)
+	}
+	bw.write(1, 1) // alpha：只有 255
+	bw.write(0, 1)
+	bw.write(1, 1)
+	bw.write(255, 8)
+	bw.write(1, 1) // distance：只有 0（不使用）
+	bw.write(0, 1)
+	bw.write(0, 1)
+	bw.write(0, 1)
+	bit := func(v uint8) uint32 {
+		if v >= 128 {
+			return 1
+		}
+		return 0
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := img.RGBAAt(x, y)
+			bw.write(bit(c.G), 1)
+			bw.write(bit(c.R), 1)
+			bw.write(bit(c.B), 1)
+		}
+	}
+
+	var chunks []byte
+	if orientation > 0 {
+		vp8x := make([]byte, 10)
+		vp8x[0] = 1 << 3 // EXIF metadata
+		vp8x[4], vp8x[5], vp8x[6] = byte(w-1), byte((w-1)>>8), byte((w-1)>>16)
+		vp8x[7], vp8x[8], vp8x[9] = byte(h-1), byte((h-1)>>8), byte((h-1)>>16)
+		chunks = append(chunks, riffChunk("VP8X", vp8x)...)
+	}
+	chunks = append(chunks, riffChunk("VP8L", bw.buf)...)
+	if orientation > 0 {
+		chunks = append(chunks, riffChunk("EXIF", exifTIFF(orientation, binary.LittleEndian))...)
+	}
+	out := append([]byte("RIFF"), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(out[4:], uint32(4+len(chunks)))
+	out = append(out, "WEBP"...)
+	return append(out, chunks...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	b := img.Bounds()
+	paletted := image.NewPaletted(b, color.Palette{color.Black, color.White})
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			paletted.Set(x, y, img.At(x, y))
+		}
+	}
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, paletted, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decodeResized 執行 Resize 並解碼輸出的 JPEG
+func decodeResized(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	out, contentType, err := Resize(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Resize: %v", err)
+	}
+	if contentType != "image/jpeg" {
+		t.Fatalf("contentType = %q，預期 image/jpeg", contentType)
+	}
+	img, err := jpeg.Decode(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("解碼輸出: %v", err)
+	}
+	return img
+}
+
+func TestEncodeWebPHelper(t *testing.T) {
+	src := markedImage(6, 4)
+	img, err := webp.Decode(bytes.NewReader(encodeWebP(src, 6)))
+	if err != nil {
+		t.Fatalf("解碼測試用 WebP: %v", err)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			got := color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+			want := color.GrayModel.Convert(src.At(x, y)).(color.Gray).Y
+			if got != want {
+				t.Fatalf("(%d,%d) = %d，預期 %d", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestResizeFormats(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h         int
+		encode       func(*testing.T, image.Image) []byte
+		wantW, wantH int
+	}{
+		{"jpeg", 64, 48, encodeJPEG, 64, 48},
+		{"jpeg 短邊超過 768", 1600, 1000, encodeJPEG, 1228, 768},
+		{"png", 64, 48, encodePNG, 64, 48},
+		{"png 長邊超過 2000", 800, 2400, encodePNG, 666, 2000},
+		{"gif", 64, 48, encodeGIF, 64, 48},
+		{"webp", 64, 48, func(_ *testing.T, img image.Image) []byte { return encodeWebP(img.(*image.RGBA), 0) }, 64, 48},
+		{"webp 短邊超過 768", 1000, 900, func(_ *testing.T, img image.Image) []byte { return encodeWebP(img.(*image.RGBA), 0) }, 853, 768},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := decodeResized(t, tt.encode(t, markedImage(tt.w, tt.h)))
+			if got := img.Bounds(); got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+				t.Fatalf("尺寸 = %dx%d，預期 %dx%d", got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+			}
+			if c := darkCorner(t, img); c != topLeft {
+				t.Fatalf("黑色區塊在 %d，預期左上角", c)
+			}
+		})
+	}
+}
+
+// orientationCases 左上角為黑色的 w x h 影像經各 Orientation 轉正後，黑色所在的角落與尺寸是否對調
+var orientationCases = []struct {
+	orientation int
+	corner      corner
+	swap        bool
+}{
+	{orientationNormal, topLeft, false},
+	{orientationFlipH, topRight, false},
+	{orientationRotate180, bottomRight, false},
+	{orientationFlipV, bottomLeft, false},
+	{orientationTranspose, topLeft, true},
+	{orientationRotate90CW, topRight, true},
+	{orientationTransverse, bottomRight, true},
+	{orientationRotate90CCW, bottomLeft, true},
+}
+
+func TestResizeOrientation(t *testing.T) {
+	const w, h = 64, 48
+	formats := []struct {
+		name   string
+		encode func(t *testing.T, orientation int) []byte
+	}{
+		{"jpeg", func(t *testing.T, orientation int) []byte {
+			return withJPEGExif(encodeJPEG(t, markedImage(w, h)), orientation)
+		}},
+		{"webp", func(_ *testing.T, orientation int) []byte {
+			return encodeWebP(markedImage(w, h), orientation)
+		}},
+	}
+	for _, f := range formats {
+		for _, tt := range orientationCases {
+			t.Run(fmt.Sprintf("%s/orientation_%d", f.name, tt.orientation), func(t *testing.T) {
+				data := f.encode(t, tt.orientation)
+				if got := exifOrientation(data); got != tt.orientation {
+					t.Fatalf("exifOrientation = %d，預期 %d", got, tt.orientation)
+				}
+				img := decodeResized(t, data)
+				wantW, wantH := w, h
+				if tt.swap {
+					wantW, wantH = h, w
+				}
+				if got := img.Bounds(); got.Dx() != wantW || got.Dy() != wantH {
+					t.Fatalf("orientation %d：尺寸 = %dx%d，預期 %dx%d", tt.orientation, got.Dx(), got.Dy(), wantW, wantH)
+				}
+				if c := darkCorner(t, img); c != tt.corner {
+					t.Fatalf("orientation %d：黑色區塊在 %d，預期 %d", tt.orientation, c, tt.corner)
+				}
+			})
+		}
+	}
+}
+
+func TestApplyOrientationPixels(t *testing.T) {
+	// 3x2 影像，每個像素的 R、G 為其座標，檢查原圖左上角 (0,0) 與右下角 (2,1) 轉正後的位置
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			src.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	tests := []struct {
+		orientation      int
+		wantW, wantH     int
+		origin, farthest image.Point
+	}{
+		{orientationNormal, 3, 2, image.Pt(0, 0), image.Pt(2, 1)},
+		{orientationFlipH, 3, 2, image.Pt(2, 0), image.Pt(0, 1)},
+		{orientationRotate180, 3, 2, image.Pt(2, 1), image.Pt(0, 0)},
+		{orientationFlipV, 3, 2, image.Pt(0, 1), image.Pt(2, 0)},
+		{orientationTranspose, 2, 3, image.Pt(0, 0), image.Pt(1, 2)},
+		{orientationRotate90CW, 2, 3, image.Pt(1, 0), image.Pt(0, 2)},
+		{orientationTransverse, 2, 3, image.Pt(1, 2), image.Pt(0, 0)},
+		{orientationRotate90CCW, 2, 3, image.Pt(0, 2), image.Pt(1, 0)},
+	}
+	for _, tt := range tests {
+		dst := applyOrientation(src, tt.orientation)
+		if b := dst.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("orientation %d：尺寸 = %dx%d，預期 %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			continue
+		}
+		if got := color.RGBAModel.Convert(dst.At(tt.origin.X, tt.origin.Y)).(color.RGBA); got.R != 0 || got.G != 0 {
+			t.Errorf("orientation %d：%v 為 (%d,%d)，預期原圖的 (0,0)", tt.orientation, tt.origin, got.R, got.G)
+		}
+		if got := color.RGBAModel.Convert(dst.At(tt.farthest.X, tt.farthest.Y)).(color.RGBA); got.R != 2 || got.G != 1 {
+			t.Errorf("orientation %d：%v 為 (%d,%d)，預期原圖的 (2,1)", tt.orientation, tt.farthest, got.R, got.G)
+		}
+	}
+}
+
+func TestResizeHEICUnsupported(t *testing.T) {
+	// 只有 ftyp box 的 HEIC 檔頭：未呼叫 RegisterHEICDecoder 時 image.Decode 無法辨識格式
+	data := []byte{0, 0, 0, 24, 'f', 't', 'y', 'p', 'h', 'e', 'i', 'c', 0, 0, 0, 0, 'm', 'i', 'f', '1', 'h', 'e', 'i', 'c'}
+	if _, _, err := Resize(bytes.NewReader(data)); !errors.Is(err, ErrHEICUnsupported) {
+		t.Fatalf("err = %v，預期 ErrHEICUnsupported", err)
+	}
+	if _, err := ResizeVariants(data, 240); !errors.Is(err, ErrHEICUnsupported) {
+		t.Fatalf("ResizeVariants err = %v，預期 ErrHEICUnsupported", err)
+	}
+}
@@ -0,0 +1,110 @@
+package imageai
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	logsvc "project/services/log"
+)
+
+// Usage 單次呼叫辨識模型的 token 用量（由各供應商的回應取得）
+type Usage struct {
+	Provider     string // openai / gemini / anthropic
+	Model        string // 實際回應的模型版本，例如 gpt-4o-mini-2024-07-18
+	InputTokens  int
+	OutputTokens int
+}
+
+// firstNonEmpty 回傳第一個非空字串（回應未帶模型版本時改用設定的模型名稱）
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Cost 依模型單價估算本次呼叫的費用（美元）；未知的模型回傳 0
+func (u *Usage) Cost() float64 {
+	if u == nil {
+		return 0
+	}
+	price, ok := priceFor(u.Model)
+	if !ok {
+		return 0
+	}
+	return (float64(u.InputTokens)*price.Input + float64(u.OutputTokens)*price.Output) / 1_000_000
+}
+
+// ModelPrice 模型單價（美元 / 每百萬 token）
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// defaultPrices 各供應商預設模型的公開牌價（美元 / 每百萬 token），以模型名稱前綴比對；
+// 可由 RECOGNITION_PRICING 覆寫或新增
+var defaultPrices = map[string]ModelPrice{
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-4.1-nano":      {Input: 0.10, Output: 0.40},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00},
+	"gemini-1.5-flash":  {Input: 0.075, Output: 0.30},
+	"gemini-2.0-flash":  {Input: 0.10, Output: 0.40},
+	"gemini-2.5-flash":  {Input: 0.30, Output: 2.50},
+	"gemini-2.5-pro":    {Input: 1.25, Output: 10.00},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00},
+	"claude-3-5-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-sonnet-4":   {Input: 3.00, Output: 15.00},
+}
+
+var (
+	prices     map[string]ModelPrice
+	pricesOnce sync.Once
+)
+
+// priceFor 取得模型單價：以最長的模型名稱前綴比對（gpt-4o-mini-2024-07-18 對應 gpt-4o-mini）
+func priceFor(model string) (ModelPrice, bool) {
+	pricesOnce.Do(func() { prices = loadPrices(os.Getenv("RECOGNITION_PRICING")) })
+	model = strings.ToLower(model)
+	var (
+		best    ModelPrice
+		bestLen int
+	)
+	for prefix, price := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			best, bestLen = price, len(prefix)
+		}
+	}
+	return best, bestLen > 0
+}
+
+// loadPrices 以預設單價為基礎套用設定值，格式為「模型=輸入單價/輸出單價」並以逗號分隔，
+// 例如 gpt-4o-mini=0.15/0.6,gemini-2.0-flash=0.1/0.4；格式錯誤的項目會略過
+func loadPrices(value string) map[string]ModelPrice {
+	out := make(map[string]ModelPrice, len(defaultPrices))
+	for model, price := range defaultPrices {
+		out[model] = price
+	}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, rates, ok := strings.Cut(entry, "=")
+		in, outRate, ok2 := strings.Cut(rates, "/")
+		inPrice, err1 := strconv.ParseFloat(strings.TrimSpace(in), 64)
+		outPrice, err2 := strconv.ParseFloat(strings.TrimSpace(outRate), 64)
+		if !ok || !ok2 || err1 != nil || err2 != nil || strings.TrimSpace(model) == "" {
+			logsvc.Warn("RECOGNITION_PRICING 格式錯誤，略過: %s", entry)
+			continue
+		}
+		out[strings.ToLower(strings.TrimSpace(model))] = ModelPrice{Input: inPrice, Output: outPrice}
+	}
+	return out
+}
@@ -0,0 +1,100 @@
+package linebot
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"project/services/imageai"
+	logsvc "project/services/log"
+	"project/services/speech"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+// handleAudioMessage 處理語音訊息：轉文字後拆解成食物並估算營養，回覆結果讓使用者確認（同圖片辨識）。
+// 群組 / 聊天室中只處理剛呼叫過 Bot 的成員所傳的語音。
+func (s *LineBotService) handleAudioMessage(event *linebot.Event, message *linebot.AudioMessage) {
+	userID := event.Source.UserID
+	if userID == "" {
+		userID = "unknown"
+	}
+	if isGroupChat(event.Source) && !s.consumeArmedImage(event.Source) {
+		return
+	}
+	s.transcribeAndReply(event, userID, message.ID)
+}
+
+// transcribeAndReply 依 LINE 訊息內容 ID 下載語音、轉文字、估算營養並回覆（附快速回覆按鈕），成功時寫入 context。
+// 語音訊息與「重新辨識」postback 共用。
+func (s *LineBotService) transcribeAndReply(event *linebot.Event, userID, contentID string) {
+	if s.transcriber == nil || s.recognizer == nil {
+		s.replyT(event, "audio.unavailable")
+		return
+	}
+	if !s.checkQuota(event, userID) {
+		return
+	}
+
+	contentResp, err := s.bot.GetMessageContent(contentID).Do()
+	if err != nil {
+		logsvc.Error("語音記錄失敗 userID=%s 取得語音失敗 err=%s", userID, err.Error())
+		s.replyT(event, "audio.download_failed")
+		return
+	}
+	defer contentResp.Content.Close()
+
+	audio, err := io.ReadAll(contentResp.Content)
+	if err != nil {
+		logsvc.Error("語音記錄失敗 userID=%s 讀取語音失敗 err=%s", userID, err.Error())
+		s.replyT(event, "audio.download_failed")
+		return
+	}
+
+	locale := s.eventLocale(event)
+	ctx := speech.WithLanguage(imageai.WithLocale(context.Background(), locale), speech.LanguageForLocale(locale))
+	ctx, cancel := context.WithTimeout(ctx, 45*time.Second)
+	defer cancel()
+
+	transcript, err := s.transcriber.Transcribe(ctx, audio, contentResp.ContentType)
+	if err != nil {
+		logsvc.Error("語音記錄失敗 userID=%s 語音轉文字失敗 err=%s", userID, err.Error())
+		s.replyT(event, "audio.transcribe_failed")
+		return
+	}
+	if transcript == "" {
+		s.replyT(event, "audio.empty")
+		return
+	}
+
+	start := time.Now()
+	result, err := s.recognizer.RecognizeText(ctx, transcript)
+	if err != nil {
+		logsvc.Error("語音記錄失敗 userID=%s API辨識失敗 err=%s", userID, err.Error())
+		s.replyT(event, recognitionErrorKey(err))
+		return
+	}
+	s.recordUsage(event, userID, imageai.SourceAudio, 0, result, time.Since(start))
+	note := s.t(event, "audio.note", transcript)
+	if !result.HasFood() {
+		s.replyText(event, note+"\n\n"+s.t(event, "audio.no_food"))
+		return
+	}
+
+	imgCtx := &imageai.UserImageContext{
+		UserID:     userID,
+		GroupID:    chatID(event.Source),
+		Source:     imageai.SourceAudio,
+		ContentID:  contentID,
+		ReplyToken: event.ReplyToken,
+		Note:       note,
+		Result:     result,
+	}
+	if err := s.replyFoodResult(event, imgCtx); err != nil {
+		logsvc.Error("語音記錄失敗 userID=%s 回覆訊息失敗 err=%s", userID, err.Error())
+		return
+	}
+
+	logsvc.Info("語音辨識成功 userID=%s", userID)
+	s.contexts.Set(imgCtx)
+}
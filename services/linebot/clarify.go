@@ -0,0 +1,96 @@
+package linebot
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"project/services/i18n"
+	"project/services/imageai"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+// quickReplyLabelMax 快速回覆按鈕 label 的字數上限（LINE 限制 20 字）
+const quickReplyLabelMax = 20
+
+// replyFoodResult 回覆 context 的辨識結果：有信心偏低的食物時先以快速回覆詢問使用者，否則回覆 Flex 卡片（附儲存等按鈕）
+func (s *LineBotService) replyFoodResult(event *linebot.Event, imgCtx *imageai.UserImageContext) error {
+	locale := s.eventLocale(event)
+	result := imgCtx.Result
+	if index := result.NeedsClarification(); index >= 0 {
+		return s.reply(event, clarifyMessage(locale, imgCtx, index))
+	}
+	quickReplies := foodActionQuickReplies(locale, imgCtx.ContentID)
+	textReply := linebot.NewTextMessage(resultText(locale, result, imgCtx.Note)).WithQuickReplies(quickReplies)
+	flexReply := buildFoodFlexMessage(locale, s.eventLocation(event), result, event.Timestamp, imgCtx.Note, imgCtx.ContentID).WithQuickReplies(quickReplies)
+	return s.replyWithFallback(event, flexReply, textReply)
+}
+
+// clarifyMessage 組出澄清問題，例如「這是 滷肉飯 還是 肉燥飯？」，每個選項一個快速回覆按鈕
+func clarifyMessage(locale string, imgCtx *imageai.UserImageContext, index int) linebot.SendingMessage {
+	result := imgCtx.Result
+	choices := result.Choices(index)
+	subject := choices[0]
+	for i, name := range choices[1:] {
+		if i == len(choices)-2 {
+			subject += i18n.T(locale, "clarify.or") + name
+		} else {
+			subject += i18n.T(locale, "clarify.separator") + name
+		}
+	}
+	if portion := result.Items[index].Portion; portion != "" {
+		subject = i18n.T(locale, "result.portion", subject, portion)
+	}
+	question := i18n.T(locale, "clarify.question", subject)
+
+	buttons := make([]*linebot.QuickReplyButton, 0, len(choices)+1)
+	for i, name := range choices {
+		data := url.Values{
+			"action":  {postbackActionClarify},
+			"content": {imgCtx.ContentID},
+			"item":    {strconv.Itoa(index)},
+			"choice":  {strconv.Itoa(i)},
+		}.Encode()
+		buttons = append(buttons, linebot.NewQuickReplyButton("",
+			linebot.NewPostbackAction(truncateRunes(name, quickReplyLabelMax), data, "", name, "", "")))
+	}
+	buttons = append(buttons, postbackActionButton(locale, "action.discard", postbackActionDiscard, imgCtx.ContentID))
+	return linebot.NewTextMessage(question).WithQuickReplies(linebot.NewQuickReplyItems(buttons...))
+}
+
+// handleClarify 以使用者的選擇更新待儲存 context 中的辨識結果，接著詢問下一個不確定的食物或回覆完整結果
+func (s *LineBotService) handleClarify(event *linebot.Event, userID string, values url.Values) {
+	imgCtx := s.contexts.Find(userID, chatID(event.Source), values.Get("content"))
+	if imgCtx == nil || imgCtx.Result == nil {
+		s.replyT(event, "context.missing")
+		return
+	}
+	index, err1 := strconv.Atoi(values.Get("item"))
+	choice, err2 := strconv.Atoi(values.Get("choice"))
+	if err1 != nil || err2 != nil {
+		logsvc.Warn("澄清 postback 參數錯誤 userID=%s data=%s", userID, event.Postback.Data)
+		return
+	}
+	if err := imgCtx.Result.Clarify(index, choice); err != nil {
+		// 多半是重新辨識後點了舊的按鈕
+		logsvc.Warn("澄清失敗 userID=%s err=%s", userID, err.Error())
+		s.replyT(event, "clarify.expired")
+		return
+	}
+	s.contexts.Set(imgCtx)
+
+	if err := s.replyFoodResult(event, imgCtx); err != nil {
+		logsvc.Error("澄清後回覆失敗 userID=%s err=%s", userID, err.Error())
+	}
+}
+
+// truncateRunes 依字元數截斷字串，超過時以「…」結尾
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
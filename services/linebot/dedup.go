@@ -0,0 +1,67 @@
+package linebot
+
+import (
+	"time"
+
+	"project/services/cache"
+	logsvc "project/services/log"
+	"project/services/redis"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+const (
+	// dedupTTL 記錄已處理 webhookEventId 的時間；LINE 重送通常在數分鐘內，保留一天足以涵蓋
+	dedupTTL       = 24 * time.Hour
+	dedupKeyPrefix = "line:webhook:event:"
+	dedupLRUSize   = 10000
+	// replyTokenTTL reply token 的有效時間（LINE 規定一分鐘內使用，保留緩衝）
+	replyTokenTTL = 50 * time.Second
+)
+
+// eventDeduper 以 webhookEventId 去除重複的 webhook 事件：優先使用 Redis SETNX（多 instance 共用），
+// Redis 不可用或出錯時改用 process 內的 LRU。
+type eventDeduper struct {
+	redis *redis.Client
+	lru   *cache.LRU
+}
+
+func newEventDeduper(redisClient *redis.Client) *eventDeduper {
+	return &eventDeduper{
+		redis: redisClient,
+		lru:   cache.NewLRU(dedupLRUSize, dedupTTL),
+	}
+}
+
+// FirstSeen 第一次看到此事件回傳 true；重複事件回傳 false。沒有 webhookEventId 的事件一律視為新事件
+func (d *eventDeduper) FirstSeen(eventID string) bool {
+	if eventID == "" {
+		return true
+	}
+	if d.redis != nil && d.redis.IsAvailable() {
+		ok, err := d.redis.SetNX(dedupKeyPrefix+eventID, 1, dedupTTL)
+		if err == nil {
+			return ok
+		}
+		logsvc.Warn("webhook 去重 Redis 失敗，改用記憶體 err=%s", err.Error())
+	}
+	return d.lru.SetNX(eventID, struct{}{})
+}
+
+// Forget 移除事件的去重紀錄（事件未能處理、需要讓 LINE 重送時使用）
+func (d *eventDeduper) Forget(eventID string) {
+	if eventID == "" {
+		return
+	}
+	if d.redis != nil && d.redis.IsAvailable() {
+		if err := d.redis.Delete(dedupKeyPrefix + eventID); err != nil {
+			logsvc.Warn("webhook 去重紀錄刪除失敗 eventID=%s err=%s", eventID, err.Error())
+		}
+	}
+	d.lru.Delete(eventID)
+}
+
+// replyTokenExpired 判斷事件的 reply token 是否已過期（重送的事件常見）
+func replyTokenExpired(event *linebot.Event, now time.Time) bool {
+	return event.ReplyToken == "" || (!event.Timestamp.IsZero() && now.Sub(event.Timestamp) > replyTokenTTL)
+}
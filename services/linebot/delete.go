@@ -0,0 +1,185 @@
+package linebot
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"project/services/i18n"
+	logsvc "project/services/log"
+	"project/services/storage"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"gorm.io/gorm"
+)
+
+// deleteLastCommands 各語系的「刪除上一張」指令（比對時不分大小寫）
+var deleteLastCommands = map[string]bool{
+	"刪除上一張":             true,
+	"删除上一张":             true,
+	"delete last":       true,
+	"delete last photo": true,
+	"前の写真を削除":           true,
+}
+
+// isDeleteLastCommand 是否為刪除最近一張照片的指令
+func isDeleteLastCommand(text string) bool {
+	return deleteLastCommands[strings.ToLower(strings.TrimSpace(text))]
+}
+
+// deletePostbackData 刪除照片的 postback data（action=delete&meal=<餐點 ID>&key=<物件 key>）
+func deletePostbackData(mealID uint, key string) string {
+	return url.Values{
+		"action": {postbackActionDelete},
+		"meal":   {strconv.FormatUint(uint64(mealID), 10)},
+		"key":    {key},
+	}.Encode()
+}
+
+// deleteMealPostbackData 從飲食日記刪除一餐的 postback data（action=delete_meal&meal=<餐點 ID>，確認後加上 confirm=1）
+func deleteMealPostbackData(mealID uint, confirmed bool) string {
+	values := url.Values{
+		"action": {postbackActionDeleteMeal},
+		"meal":   {strconv.FormatUint(uint64(mealID), 10)},
+	}
+	if confirmed {
+		values.Set("confirm", "1")
+	}
+	return values.Encode()
+}
+
+// handleDeleteLastCommand 找出使用者最近儲存的照片（一組照片時為最後一張），以快速回覆按鈕確認後才刪除
+func (s *LineBotService) handleDeleteLastCommand(event *linebot.Event, userID string) {
+	if s.db == nil {
+		s.replyT(event, "diary.unavailable")
+		return
+	}
+	meal, err := s.db.LatestMealWithImage(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.replyT(event, "delete.none")
+		return
+	}
+	if err != nil {
+		logsvc.Error("查詢最近的照片失敗 userID=%s err=%s", userID, err.Error())
+		s.replyT(event, "delete.failed")
+		return
+	}
+
+	key := meal.S3Key
+	if n := len(meal.Images); n > 0 {
+		key = meal.Images[n-1].S3Key
+	}
+	locale := s.eventLocale(event)
+	question := i18n.T(locale, "delete.confirm",
+		meal.EatenAt.In(s.userLocation(userID)).Format("01/02 15:04"), mealName(locale, meal.MealType), meal.TotalKcal)
+	label := i18n.T(locale, "action.delete")
+	button := linebot.NewQuickReplyButton("", linebot.NewPostbackAction(label, deletePostbackData(meal.ID, key), "", label, "", ""))
+	message := linebot.NewTextMessage(question).WithQuickReplies(linebot.NewQuickReplyItems(button))
+	if err := s.reply(event, message); err != nil {
+		logsvc.Error("回覆刪除確認失敗 userID=%s err=%s", userID, err.Error())
+	}
+}
+
+// handleDeleteImage 刪除餐點中的照片（postback 的 meal、key 參數）：先移除 DB 紀錄，
+// 使用者沒有其他餐點引用同一張照片時再刪除儲存空間中的原圖與各版本
+func (s *LineBotService) handleDeleteImage(event *linebot.Event, userID string, values url.Values) {
+	mealID, err := strconv.ParseUint(values.Get("meal"), 10, 64)
+	key := values.Get("key")
+	if err != nil || mealID == 0 || key == "" {
+		logsvc.Warn("刪除照片 postback 參數錯誤 userID=%s data=%s", userID, event.Postback.Data)
+		s.replyT(event, "delete.none")
+		return
+	}
+	if s.db == nil {
+		s.replyT(event, "diary.unavailable")
+		return
+	}
+
+	affected, remaining, err := s.db.DeleteMealImage(userID, key, uint(mealID))
+	if err != nil {
+		logsvc.Error("刪除餐點照片失敗 userID=%s mealID=%d key=%s err=%s", userID, mealID, key, err.Error())
+		s.replyT(event, "delete.failed")
+		return
+	}
+	if affected == 0 {
+		// 已刪除過（重複按下按鈕）或不是自己的餐點
+		s.replyT(event, "delete.none")
+		return
+	}
+	if remaining == 0 && s.storage != nil && strings.HasPrefix(key, storage.ImagePrefix(userID)) {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := storage.DeleteImage(ctx, s.storage, key); err != nil {
+			logsvc.Error("刪除儲存空間的照片失敗 userID=%s key=%s err=%s", userID, key, err.Error())
+		}
+	}
+	logsvc.Info("已刪除照片 userID=%s mealID=%d key=%s", userID, mealID, key)
+	s.replyT(event, "delete.done")
+}
+
+// handleDeleteMeal 處理飲食日記的刪除按鈕（postback 的 meal、confirm 參數）：尚未確認時回覆確認訊息；
+// 確認後刪除這一餐與其食物明細，使用者沒有其他餐點引用的照片一併從儲存空間刪除
+func (s *LineBotService) handleDeleteMeal(event *linebot.Event, userID string, values url.Values) {
+	mealID, err := strconv.ParseUint(values.Get("meal"), 10, 64)
+	if err != nil || mealID == 0 {
+		logsvc.Warn("刪除餐點 postback 參數錯誤 userID=%s data=%s", userID, event.Postback.Data)
+		s.replyT(event, "delete.meal_none")
+		return
+	}
+	if s.db == nil {
+		s.replyT(event, "diary.unavailable")
+		return
+	}
+
+	if values.Get("confirm") != "1" {
+		meal, err := s.db.GetMeal(userID, uint(mealID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.replyT(event, "delete.meal_none")
+			return
+		}
+		if err != nil {
+			logsvc.Error("查詢餐點失敗 userID=%s mealID=%d err=%s", userID, mealID, err.Error())
+			s.replyT(event, "delete.failed")
+			return
+		}
+		locale := s.eventLocale(event)
+		question := i18n.T(locale, "delete.meal_confirm",
+			meal.EatenAt.In(s.userLocation(userID)).Format("01/02 15:04"), mealName(locale, meal.MealType), meal.TotalKcal)
+		label := i18n.T(locale, "action.delete")
+		button := linebot.NewQuickReplyButton("", linebot.NewPostbackAction(label, deleteMealPostbackData(meal.ID, true), "", label, "", ""))
+		message := linebot.NewTextMessage(question).WithQuickReplies(linebot.NewQuickReplyItems(button))
+		if err := s.reply(event, message); err != nil {
+			logsvc.Error("回覆刪除確認失敗 userID=%s err=%s", userID, err.Error())
+		}
+		return
+	}
+
+	orphaned, err := s.db.DeleteMeal(userID, uint(mealID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 已刪除過（重複按下按鈕）或不是自己的餐點
+		s.replyT(event, "delete.meal_none")
+		return
+	}
+	if err != nil {
+		logsvc.Error("刪除餐點失敗 userID=%s mealID=%d err=%s", userID, mealID, err.Error())
+		s.replyT(event, "delete.failed")
+		return
+	}
+	if s.storage != nil && len(orphaned) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		for _, key := range orphaned {
+			if !strings.HasPrefix(key, storage.ImagePrefix(userID)) {
+				continue
+			}
+			if err := storage.DeleteImage(ctx, s.storage, key); err != nil {
+				logsvc.Error("刪除儲存空間的照片失敗 userID=%s key=%s err=%s", userID, key, err.Error())
+			}
+		}
+	}
+	logsvc.Info("已刪除餐點 userID=%s mealID=%d images=%d", userID, mealID, len(orphaned))
+	s.replyT(event, "delete.meal_done")
+}
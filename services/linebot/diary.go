@@ -0,0 +1,191 @@
+package linebot
+
+import (
+	"strings"
+	"time"
+
+	"project/models"
+	"project/services/i18n"
+	"project/services/imageai"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+// maxDiaryReplyRunes 飲食日記回覆的字數上限（LINE 文字訊息上限 5000 字）
+const maxDiaryReplyRunes = 4800
+
+// maxDiaryDeleteButtons 飲食日記下方「刪除」快速回覆按鈕的數量上限（LINE 限制 13 個）
+const maxDiaryDeleteButtons = 13
+
+// diaryPeriod 飲食日記查詢區間（值為 i18n 鍵的後綴，顯示時依語系轉成文字）
+type diaryPeriod string
+
+const (
+	diaryToday     diaryPeriod = "today"
+	diaryYesterday diaryPeriod = "yesterday"
+	diaryThisWeek  diaryPeriod = "this_week"
+)
+
+// diaryCommands 各語系的飲食日記查詢指令（比對時不分大小寫）
+var diaryCommands = map[string]diaryPeriod{
+	"今天":        diaryToday,
+	"today":     diaryToday,
+	"今日":        diaryToday,
+	"昨天":        diaryYesterday,
+	"yesterday": diaryYesterday,
+	"昨日":        diaryYesterday,
+	"本週":        diaryThisWeek,
+	"本周":        diaryThisWeek,
+	"this week": diaryThisWeek,
+	"今週":        diaryThisWeek,
+}
+
+// parseDiaryPeriod 判斷文字是否為飲食日記查詢指令（今天 / 昨天 / 本週，或其他語系的同義指令）
+func parseDiaryPeriod(text string) (diaryPeriod, bool) {
+	p, ok := diaryCommands[strings.ToLower(strings.TrimSpace(text))]
+	return p, ok
+}
+
+// Label 查詢區間在該語系的名稱
+func (p diaryPeriod) Label(locale string) string {
+	return i18n.T(locale, "diary."+string(p))
+}
+
+// Range 回傳查詢區間 [from, to)，以使用者時區（loc）的日界線計算；本週從週一開始
+func (p diaryPeriod) Range(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch p {
+	case diaryYesterday:
+		return today.AddDate(0, 0, -1), today
+	case diaryThisWeek:
+		offset := (int(today.Weekday()) + 6) % 7 // 週一為 0
+		return today.AddDate(0, 0, -offset), today.AddDate(0, 0, 1)
+	default:
+		return today, today.AddDate(0, 0, 1)
+	}
+}
+
+// newMeal 將辨識 context 與儲存空間的物件 key（一組照片時依傳送順序）轉成要寫入的 Meal，餐別依使用者時區（loc）判斷
+func newMeal(imgCtx *imageai.UserImageContext, s3Keys []string, loc *time.Location) *models.Meal {
+	eatenAt := time.Unix(imgCtx.RecognizedAt, 0)
+	meal := &models.Meal{
+		LineUserID: imgCtx.UserID,
+		GroupID:    imgCtx.GroupID,
+		EatenAt:    eatenAt,
+		MealType:   mealCode(eatenAt, loc),
+		ContentID:  imgCtx.ContentID,
+	}
+	contentIDs := imgCtx.AllContentIDs()
+	for i, key := range s3Keys {
+		if i == 0 {
+			meal.S3Key = key
+		}
+		image := models.MealImage{S3Key: key, Position: i}
+		if i < len(contentIDs) {
+			image.ContentID = contentIDs[i]
+		}
+		meal.Images = append(meal.Images, image)
+	}
+	if imgCtx.Result == nil {
+		return meal
+	}
+	total := imgCtx.Result.Total()
+	meal.TotalKcal = total.Kcal
+	meal.TotalProtein = total.Protein
+	meal.TotalFat = total.Fat
+	meal.TotalCarbs = total.Carbs
+	for _, item := range imgCtx.Result.Items {
+		meal.Items = append(meal.Items, models.MealItem{
+			Name:       item.Name,
+			Portion:    item.Portion,
+			Kcal:       item.Kcal,
+			Protein:    item.Protein,
+			Fat:        item.Fat,
+			Carbs:      item.Carbs,
+			Confidence: item.Confidence,
+		})
+	}
+	return meal
+}
+
+// handleDiaryQuery 回覆使用者在指定區間記錄的餐點
+func (s *LineBotService) handleDiaryQuery(event *linebot.Event, userID string, period diaryPeriod) {
+	if s.db == nil {
+		s.replyT(event, "diary.unavailable")
+		return
+	}
+	loc := s.userLocation(userID)
+	from, to := period.Range(time.Now(), loc)
+	meals, err := s.db.ListMeals(userID, from, to)
+	if err != nil {
+		logsvc.Error("查詢飲食日記失敗 userID=%s err=%s", userID, err.Error())
+		s.replyT(event, "diary.query_failed")
+		return
+	}
+	locale := s.userLocale(userID)
+	var message linebot.SendingMessage = linebot.NewTextMessage(formatDiary(locale, loc, period, from, to, meals))
+	if len(meals) > 0 {
+		message = message.WithQuickReplies(diaryDeleteQuickReplies(locale, loc, to.Sub(from) > 24*time.Hour, meals))
+	}
+	if err := s.reply(event, message); err != nil {
+		logsvc.Error("回覆飲食日記失敗 userID=%s err=%s", userID, err.Error())
+	}
+}
+
+// diaryDeleteQuickReplies 飲食日記下方每一餐的「刪除」快速回覆按鈕，由新到舊最多 maxDiaryDeleteButtons 個；
+// 按下後先回覆確認訊息，確認後才刪除（見 handleDeleteMeal）
+func diaryDeleteQuickReplies(locale string, loc *time.Location, multiDay bool, meals []models.Meal) *linebot.QuickReplyItems {
+	layout := "15:04"
+	if multiDay {
+		layout = "01/02 15:04"
+	}
+	buttons := make([]*linebot.QuickReplyButton, 0, maxDiaryDeleteButtons)
+	for i := len(meals) - 1; i >= 0 && len(buttons) < maxDiaryDeleteButtons; i-- {
+		meal := meals[i]
+		label := i18n.T(locale, "diary.delete_label", meal.EatenAt.In(loc).Format(layout), mealName(locale, meal.MealType))
+		buttons = append(buttons, linebot.NewQuickReplyButton("",
+			linebot.NewPostbackAction(truncateRunes(label, quickReplyLabelMax), deleteMealPostbackData(meal.ID, false), "", label, "", "")))
+	}
+	return linebot.NewQuickReplyItems(buttons...)
+}
+
+// formatDiary 將餐點列表整理成文字：每餐一行時間（使用者時區 loc）、餐別與熱量，下一行為食物名稱，最後為合計
+func formatDiary(locale string, loc *time.Location, period diaryPeriod, from, to time.Time, meals []models.Meal) string {
+	title := i18n.T(locale, "diary.title", period.Label(locale), from.Format("01/02"))
+	if to.Sub(from) > 24*time.Hour {
+		title = i18n.T(locale, "diary.title_range", period.Label(locale), from.Format("01/02"), to.AddDate(0, 0, -1).Format("01/02"))
+	}
+	if len(meals) == 0 {
+		return title + "\n\n" + i18n.T(locale, "diary.empty")
+	}
+
+	var b strings.Builder
+	b.WriteString(title)
+	multiDay := to.Sub(from) > 24*time.Hour
+	var totalKcal float64
+	for _, meal := range meals {
+		eatenAt := meal.EatenAt.In(loc)
+		layout := "15:04"
+		if multiDay {
+			layout = "01/02 15:04"
+		}
+		names := make([]string, 0, len(meal.Items))
+		for _, item := range meal.Items {
+			names = append(names, item.Name)
+		}
+		b.WriteString("\n\n" + i18n.T(locale, "diary.meal", eatenAt.Format(layout), mealName(locale, meal.MealType), meal.TotalKcal))
+		if len(names) > 0 {
+			b.WriteString("\n  " + strings.Join(names, i18n.T(locale, "diary.names_separator")))
+		}
+		totalKcal += meal.TotalKcal
+	}
+	footer := "\n\n" + i18n.T(locale, "diary.total", len(meals), totalKcal)
+
+	text := []rune(b.String())
+	if len(text)+len([]rune(footer)) > maxDiaryReplyRunes {
+		text = append(text[:maxDiaryReplyRunes-len([]rune(footer))-2], []rune("\n…")...)
+	}
+	return string(text) + footer
+}
@@ -0,0 +1,58 @@
+package linebot
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"project/models"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+func TestDiaryDeleteQuickReplies(t *testing.T) {
+	base := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	meals := make([]models.Meal, 15)
+	for i := range meals {
+		meals[i] = models.Meal{ID: uint(i + 1), EatenAt: base.Add(time.Duration(i) * time.Hour), MealType: "lunch"}
+	}
+
+	items := diaryDeleteQuickReplies("zh-TW", time.UTC, false, meals)
+	if len(items.Items) != maxDiaryDeleteButtons {
+		t.Fatalf("buttons = %d, want %d", len(items.Items), maxDiaryDeleteButtons)
+	}
+	// 由新到舊：第一個按鈕為最後一餐
+	for i, item := range items.Items {
+		action, ok := item.Action.(*linebot.PostbackAction)
+		if !ok {
+			t.Fatalf("button %d action = %T", i, item.Action)
+		}
+		if len([]rune(action.Label)) > quickReplyLabelMax {
+			t.Errorf("label %q longer than %d runes", action.Label, quickReplyLabelMax)
+		}
+		values, err := url.ParseQuery(action.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantID := len(meals) - i
+		if values.Get("action") != postbackActionDeleteMeal || values.Get("meal") != strconv.Itoa(wantID) || values.Has("confirm") {
+			t.Errorf("button %d data = %q, want meal %d without confirm", i, action.Data, wantID)
+		}
+	}
+	if label := items.Items[0].Action.(*linebot.PostbackAction).Label; label != "刪除 14:00 午餐" {
+		t.Errorf("first label = %q", label)
+	}
+}
+
+func TestDeleteMealPostbackData(t *testing.T) {
+	for _, confirmed := range []bool{false, true} {
+		values, err := url.ParseQuery(deleteMealPostbackData(42, confirmed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values.Get("action") != postbackActionDeleteMeal || values.Get("meal") != "42" || (values.Get("confirm") == "1") != confirmed {
+			t.Errorf("deleteMealPostbackData(42, %v) = %v", confirmed, values)
+		}
+	}
+}
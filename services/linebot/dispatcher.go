@@ -0,0 +1,201 @@
+package linebot
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultWorkerCount = 4
+	defaultQueueSize   = 100
+)
+
+var (
+	// ErrQueueFull 佇列已滿，事件未被接受
+	ErrQueueFull = errors.New("事件佇列已滿")
+	// ErrDispatcherClosed 服務關閉中，不再接受新事件
+	ErrDispatcherClosed = errors.New("事件佇列已關閉")
+)
+
+// DispatcherStats 事件佇列的即時指標
+type DispatcherStats struct {
+	Workers       int    `json:"workers"`
+	QueueCapacity int    `json:"queue_capacity"`
+	QueueDepth    int    `json:"queue_depth"` // 等待處理的事件數
+	InFlight      int64  `json:"in_flight"`   // 處理中的事件數
+	Processed     uint64 `json:"processed"`
+	Rejected      uint64 `json:"rejected"` // 因佇列已滿或關閉而拒絕的事件數
+}
+
+// dispatchJob 佇列中的工作：webhook 事件，或以 run 指定的內部工作（例如照片組等待逾時後的辨識）
+type dispatchJob struct {
+	event *linebot.Event
+	run   func()
+}
+
+// eventDispatcher 固定數量的 worker 處理 webhook 事件：
+// 每個 worker 有自己的有界佇列，同一個來源（使用者 / 群組）的事件固定分派到同一個 worker，因此會依序處理；
+// 佇列滿時直接拒絕，避免大量圖片同時解碼與呼叫辨識 API。
+type eventDispatcher struct {
+	queues []chan dispatchJob
+	handle func(*linebot.Event)
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex // 保護 closed 與 channel 關閉
+	closed bool
+
+	inFlight  atomic.Int64
+	processed atomic.Uint64
+	rejected  atomic.Uint64
+}
+
+// newEventDispatcher 建立並啟動 worker；queueSize 為所有 worker 佇列容量的總和
+func newEventDispatcher(workers, queueSize int, handle func(*linebot.Event)) *eventDispatcher {
+	if workers <= 0 {
+		workers = defaultWorkerCount
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	perWorker := (queueSize + workers - 1) / workers
+
+	d := &eventDispatcher{
+		queues: make([]chan dispatchJob, workers),
+		handle: handle,
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan dispatchJob, perWorker)
+		d.wg.Add(1)
+		go d.work(d.queues[i])
+	}
+	return d
+}
+
+// newEventDispatcherFromEnv 從環境變數 LINE_WORKER_COUNT、LINE_QUEUE_SIZE 建立 dispatcher
+func newEventDispatcherFromEnv(handle func(*linebot.Event)) *eventDispatcher {
+	return newEventDispatcher(viper.GetInt("Line.Worker.Count"), viper.GetInt("Line.Queue.Size"), handle)
+}
+
+// Dispatch 將事件放入對應來源的佇列（不阻塞），佇列已滿或已關閉時回傳錯誤
+func (d *eventDispatcher) Dispatch(event *linebot.Event) error {
+	return d.enqueue(event.Source, dispatchJob{event: event})
+}
+
+// DispatchFunc 將內部工作放入 source 對應的佇列，與該來源的事件依序處理並受 worker 數量限制
+func (d *eventDispatcher) DispatchFunc(source *linebot.EventSource, run func()) error {
+	return d.enqueue(source, dispatchJob{run: run})
+}
+
+// enqueue 將工作放入來源對應的佇列（不阻塞）
+func (d *eventDispatcher) enqueue(source *linebot.EventSource, job dispatchJob) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		d.rejected.Add(1)
+		return ErrDispatcherClosed
+	}
+	select {
+	case d.queues[d.shard(source)] <- job:
+		return nil
+	default:
+		d.rejected.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Stats 回傳目前的佇列指標
+func (d *eventDispatcher) Stats() DispatcherStats {
+	stats := DispatcherStats{
+		Workers:   len(d.queues),
+		InFlight:  d.inFlight.Load(),
+		Processed: d.processed.Load(),
+		Rejected:  d.rejected.Load(),
+	}
+	for _, q := range d.queues {
+		stats.QueueCapacity += cap(q)
+		stats.QueueDepth += len(q)
+	}
+	return stats
+}
+
+// Shutdown 停止接受新事件，並等待佇列中與處理中的事件完成；ctx 逾時則回傳 ctx.Err()
+func (d *eventDispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		for _, q := range d.queues {
+			close(q)
+		}
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *eventDispatcher) work(queue <-chan dispatchJob) {
+	defer d.wg.Done()
+	for job := range queue {
+		d.process(job)
+	}
+}
+
+// process 處理單一工作，panic 時記錄錯誤並讓 worker 繼續處理下一個工作
+func (d *eventDispatcher) process(job dispatchJob) {
+	d.inFlight.Add(1)
+	defer func() {
+		d.inFlight.Add(-1)
+		d.processed.Add(1)
+		if err := recover(); err != nil {
+			if job.event != nil {
+				logsvc.Error("處理事件 panic eventID=%s err=%v", job.event.WebhookEventID, err)
+			} else {
+				logsvc.Error("處理內部工作 panic err=%v", err)
+			}
+		}
+	}()
+	if job.run != nil {
+		job.run()
+		return
+	}
+	d.handle(job.event)
+}
+
+// shard 依事件來源決定 worker（同一來源固定同一個 worker，確保處理順序）
+func (d *eventDispatcher) shard(source *linebot.EventSource) int {
+	h := fnv.New32a()
+	h.Write([]byte(sourceKey(source)))
+	return int(h.Sum32() % uint32(len(d.queues)))
+}
+
+// sourceKey 事件來源的識別字串：群組 / 聊天室以其 ID 為準，否則為使用者 ID
+func sourceKey(source *linebot.EventSource) string {
+	if source == nil {
+		return ""
+	}
+	switch {
+	case source.GroupID != "":
+		return "group:" + source.GroupID
+	case source.RoomID != "":
+		return "room:" + source.RoomID
+	default:
+		return "user:" + source.UserID
+	}
+}
@@ -0,0 +1,237 @@
+package linebot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+func testEvent(source *linebot.EventSource, id string) *linebot.Event {
+	return &linebot.Event{Source: source, WebhookEventID: id}
+}
+
+func userSource(userID string) *linebot.EventSource {
+	return &linebot.EventSource{Type: linebot.EventSourceTypeUser, UserID: userID}
+}
+
+func TestDispatcherPerSourceOrdering(t *testing.T) {
+	const sources, perSource = 8, 50
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	// 每個 shard 容量為 queueSize/workers，同一來源只會進入一個 shard
+	d := newEventDispatcher(4, 4*sources*perSource, func(event *linebot.Event) {
+		source, seq, _ := strings.Cut(event.WebhookEventID, "/")
+		n, _ := strconv.Atoi(seq)
+		if n%7 == 0 {
+			time.Sleep(time.Millisecond) // 讓不同 worker 的進度交錯
+		}
+		mu.Lock()
+		seen[source] = append(seen[source], n)
+		mu.Unlock()
+	})
+
+	// 群組與聊天室以其 ID 分派，同一群組內不同使用者的事件也要依序處理
+	sourceOf := func(i int) *linebot.EventSource {
+		switch i % 3 {
+		case 0:
+			return &linebot.EventSource{Type: linebot.EventSourceTypeGroup, GroupID: fmt.Sprintf("G%d", i), UserID: fmt.Sprintf("U%d", i)}
+		case 1:
+			return &linebot.EventSource{Type: linebot.EventSourceTypeRoom, RoomID: fmt.Sprintf("R%d", i)}
+		default:
+			return userSource(fmt.Sprintf("U%d", i))
+		}
+	}
+	for n := 0; n < perSource; n++ {
+		for i := 0; i < sources; i++ {
+			source := sourceOf(i)
+			if source.GroupID != "" {
+				source.UserID = fmt.Sprintf("U%d-%d", i, n%3)
+			}
+			if err := d.Dispatch(testEvent(source, fmt.Sprintf("%s/%d", sourceKey(source), n))); err != nil {
+				t.Fatalf("Dispatch: %v", err)
+			}
+		}
+	}
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != sources {
+		t.Fatalf("sources = %d, want %d", len(seen), sources)
+	}
+	for source, order := range seen {
+		if len(order) != perSource {
+			t.Errorf("%s: processed %d events, want %d", source, len(order), perSource)
+		}
+		for i, n := range order {
+			if n != i {
+				t.Errorf("%s: order %v is not sequential", source, order)
+				break
+			}
+		}
+	}
+	if stats := d.Stats(); stats.Processed != sources*perSource || stats.Rejected != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestDispatcherFuncSharesSourceQueue(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(s string) {
+		mu.Lock()
+		order = append(order, s)
+		mu.Unlock()
+	}
+	d := newEventDispatcher(4, 4*20, func(event *linebot.Event) { record(event.WebhookEventID) })
+	source := userSource("U1")
+	for i := 0; i < 10; i++ {
+		if err := d.Dispatch(testEvent(source, fmt.Sprintf("event-%d", i))); err != nil {
+			t.Fatal(err)
+		}
+		i := i
+		if err := d.DispatchFunc(source, func() { record(fmt.Sprintf("func-%d", i)) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if order[2*i] != fmt.Sprintf("event-%d", i) || order[2*i+1] != fmt.Sprintf("func-%d", i) {
+			t.Fatalf("order = %v", order)
+		}
+	}
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	d := newEventDispatcher(1, 2, func(event *linebot.Event) {
+		started <- struct{}{}
+		<-release
+	})
+	source := userSource("U1")
+
+	// 第一個事件由 worker 取出處理中，佇列還能放 2 個，第 4 個被拒絕
+	if err := d.Dispatch(testEvent(source, "1")); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	for _, id := range []string{"2", "3"} {
+		if err := d.Dispatch(testEvent(source, id)); err != nil {
+			t.Fatalf("Dispatch %s: %v", id, err)
+		}
+	}
+	if err := d.Dispatch(testEvent(source, "4")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Dispatch 4 = %v, want ErrQueueFull", err)
+	}
+	if err := d.DispatchFunc(source, func() {}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("DispatchFunc = %v, want ErrQueueFull", err)
+	}
+	stats := d.Stats()
+	if stats.QueueDepth != 2 || stats.InFlight != 1 || stats.Rejected != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	go func() {
+		for range started {
+		}
+	}()
+	close(release)
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(started)
+	if stats := d.Stats(); stats.Processed != 3 || stats.QueueDepth != 0 || stats.InFlight != 0 {
+		t.Errorf("stats after shutdown = %+v", stats)
+	}
+}
+
+func TestDispatcherShutdownDrains(t *testing.T) {
+	var mu sync.Mutex
+	handled := 0
+	d := newEventDispatcher(2, 2*20, func(event *linebot.Event) {
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		handled++
+		mu.Unlock()
+	})
+	for i := 0; i < 20; i++ {
+		if err := d.Dispatch(testEvent(userSource(fmt.Sprintf("U%d", i%5)), strconv.Itoa(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if handled != 20 {
+		t.Errorf("handled = %d, want 20 (queued events must finish before Shutdown returns)", handled)
+	}
+	if err := d.Dispatch(testEvent(userSource("U1"), "late")); !errors.Is(err, ErrDispatcherClosed) {
+		t.Errorf("Dispatch after Shutdown = %v, want ErrDispatcherClosed", err)
+	}
+	if err := d.DispatchFunc(userSource("U1"), func() {}); !errors.Is(err, ErrDispatcherClosed) {
+		t.Errorf("DispatchFunc after Shutdown = %v, want ErrDispatcherClosed", err)
+	}
+	// 重複呼叫 Shutdown 不會 panic
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown = %v", err)
+	}
+}
+
+func TestDispatcherShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	d := newEventDispatcher(1, 1, func(event *linebot.Event) {
+		close(started)
+		<-release
+	})
+	if err := d.Dispatch(testEvent(userSource("U1"), "1")); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want context.DeadlineExceeded", err)
+	}
+	close(release)
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown after release = %v", err)
+	}
+}
+
+func TestDispatcherRecoversPanic(t *testing.T) {
+	var mu sync.Mutex
+	var handled []string
+	d := newEventDispatcher(1, 10, func(event *linebot.Event) {
+		if event.WebhookEventID == "boom" {
+			panic("boom")
+		}
+		mu.Lock()
+		handled = append(handled, event.WebhookEventID)
+		mu.Unlock()
+	})
+	for _, id := range []string{"1", "boom", "2"} {
+		if err := d.Dispatch(testEvent(userSource("U1"), id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(handled, ",") != "1,2" {
+		t.Errorf("handled = %v, want [1 2]", handled)
+	}
+	if stats := d.Stats(); stats.Processed != 3 {
+		t.Errorf("processed = %d, want 3", stats.Processed)
+	}
+}
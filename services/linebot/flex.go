@@ -0,0 +1,202 @@
+package linebot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"project/services/i18n"
+	"project/services/imageai"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+// taipeiLocation 使用者未設定時區時的預設時區（容器未安裝 tzdata 時以 UTC+8 代替）
+var taipeiLocation = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		return time.FixedZone("Asia/Taipei", 8*60*60)
+	}
+	return loc
+}()
+
+// 餐別代碼（寫入 meals.meal_type，顯示時依語系轉成文字）
+const (
+	mealBreakfast = "breakfast"
+	mealLunch     = "lunch"
+	mealDinner    = "dinner"
+	mealSnack     = "snack"
+)
+
+// legacyMealTypes 舊資料以中文儲存的餐別
+var legacyMealTypes = map[string]string{
+	"早餐": mealBreakfast,
+	"午餐": mealLunch,
+	"晚餐": mealDinner,
+	"點心": mealSnack,
+}
+
+// mealCode 依使用者時區（loc）的時間判斷餐別
+func mealCode(t time.Time, loc *time.Location) string {
+	switch h := t.In(loc).Hour(); {
+	case h >= 5 && h < 10:
+		return mealBreakfast
+	case h >= 10 && h < 14:
+		return mealLunch
+	case h >= 17 && h < 21:
+		return mealDinner
+	default:
+		return mealSnack
+	}
+}
+
+// mealName 餐別代碼（或舊資料的中文餐別）在該語系的名稱
+func mealName(locale, mealType string) string {
+	if code, ok := legacyMealTypes[mealType]; ok {
+		mealType = code
+	}
+	return i18n.T(locale, "meal."+mealType)
+}
+
+// buildFoodFlexMessage 將辨識結果組成 Flex 卡片：header 顯示餐別與時間（note 不為空時附在下方，例如語音轉出的文字）、
+// body 每項食物一列（名稱、份量、熱量）並附上合計營養素、footer 為動作按鈕（postback 帶 contentID，指向這張卡片的 context）。
+// altText 沿用純文字結果，不支援 Flex 的客戶端與通知預覽會顯示此文字。餐別與時間依使用者時區（loc）顯示。
+func buildFoodFlexMessage(locale string, loc *time.Location, result *imageai.RecognitionResult, at time.Time, note, contentID string) *linebot.FlexMessage {
+	header := &linebot.BoxComponent{
+		Type:   linebot.FlexComponentTypeBox,
+		Layout: linebot.FlexBoxLayoutTypeVertical,
+		Contents: []linebot.FlexComponent{
+			&linebot.TextComponent{
+				Type:   linebot.FlexComponentTypeText,
+				Text:   i18n.T(locale, "result.title", mealName(locale, mealCode(at, loc))),
+				Size:   linebot.FlexTextSizeTypeLg,
+				Weight: linebot.FlexTextWeightTypeBold,
+			},
+			&linebot.TextComponent{
+				Type:  linebot.FlexComponentTypeText,
+				Text:  at.In(loc).Format("2006/01/02 15:04"),
+				Size:  linebot.FlexTextSizeTypeXs,
+				Color: "#999999",
+			},
+		},
+	}
+	if note != "" {
+		header.Contents = append(header.Contents, &linebot.TextComponent{
+			Type:   linebot.FlexComponentTypeText,
+			Text:   note,
+			Size:   linebot.FlexTextSizeTypeSm,
+			Color:  "#666666",
+			Wrap:   true,
+			Margin: linebot.FlexComponentMarginTypeSm,
+		})
+	}
+
+	rows := make([]linebot.FlexComponent, 0, len(result.Items)+2)
+	for _, item := range result.Items {
+		rows = append(rows, &linebot.BoxComponent{
+			Type:    linebot.FlexComponentTypeBox,
+			Layout:  linebot.FlexBoxLayoutTypeBaseline,
+			Spacing: linebot.FlexComponentSpacingTypeSm,
+			Contents: []linebot.FlexComponent{
+				&linebot.TextComponent{
+					Type: linebot.FlexComponentTypeText,
+					Text: itemName(locale, item),
+					Size: linebot.FlexTextSizeTypeSm,
+					Wrap: true,
+					Flex: linebot.IntPtr(7),
+				},
+				&linebot.TextComponent{
+					Type:  linebot.FlexComponentTypeText,
+					Text:  fmt.Sprintf("%.0f kcal", item.Kcal),
+					Size:  linebot.FlexTextSizeTypeSm,
+					Color: "#666666",
+					Align: linebot.FlexComponentAlignTypeEnd,
+					Flex:  linebot.IntPtr(3),
+				},
+			},
+		})
+	}
+	total := result.Total()
+	rows = append(rows,
+		&linebot.SeparatorComponent{Type: linebot.FlexComponentTypeSeparator, Margin: linebot.FlexComponentMarginTypeMd},
+		&linebot.TextComponent{
+			Type:   linebot.FlexComponentTypeText,
+			Text:   i18n.T(locale, "result.total", total.Kcal, total.Protein, total.Fat, total.Carbs),
+			Size:   linebot.FlexTextSizeTypeSm,
+			Weight: linebot.FlexTextWeightTypeBold,
+			Wrap:   true,
+			Margin: linebot.FlexComponentMarginTypeMd,
+		},
+	)
+	body := &linebot.BoxComponent{
+		Type:     linebot.FlexComponentTypeBox,
+		Layout:   linebot.FlexBoxLayoutTypeVertical,
+		Spacing:  linebot.FlexComponentSpacingTypeSm,
+		Contents: rows,
+	}
+
+	footer := &linebot.BoxComponent{
+		Type:    linebot.FlexComponentTypeBox,
+		Layout:  linebot.FlexBoxLayoutTypeHorizontal,
+		Spacing: linebot.FlexComponentSpacingTypeSm,
+		Contents: []linebot.FlexComponent{
+			flexPostbackButton(i18n.T(locale, "action.save"), postbackData(postbackActionSave, contentID), linebot.FlexButtonStyleTypePrimary),
+			flexPostbackButton(i18n.T(locale, "action.retry"), postbackData(postbackActionRetry, contentID), linebot.FlexButtonStyleTypeSecondary),
+			flexPostbackButton(i18n.T(locale, "action.discard"), postbackData(postbackActionDiscard, contentID), linebot.FlexButtonStyleTypeSecondary),
+		},
+	}
+
+	return linebot.NewFlexMessage(resultText(locale, result, note), &linebot.BubbleContainer{
+		Type:   linebot.FlexContainerTypeBubble,
+		Header: header,
+		Body:   body,
+		Footer: footer,
+	})
+}
+
+// flexPostbackButton 建立送出 postback 的 Flex 按鈕
+func flexPostbackButton(label, data string, style linebot.FlexButtonStyleType) *linebot.ButtonComponent {
+	return &linebot.ButtonComponent{
+		Type:   linebot.FlexComponentTypeButton,
+		Style:  style,
+		Height: linebot.FlexButtonHeightTypeSm,
+		Action: linebot.NewPostbackAction(label, data, "", label, "", ""),
+	}
+}
+
+// replyWithFallback 先以 primary（Flex）回覆；若 LINE 拒絕此訊息（400）則改以 fallback（純文字）回覆。
+// 被拒絕的 reply token 尚未使用，仍可再次回覆。
+func (s *LineBotService) replyWithFallback(event *linebot.Event, primary, fallback linebot.SendingMessage) error {
+	err := s.reply(event, primary)
+	var apiErr *linebot.APIError
+	if err != nil && errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest {
+		logsvc.Warn("Flex 訊息回覆失敗，改用純文字 err=%s", err.Error())
+		err = s.reply(event, fallback)
+	}
+	return err
+}
+
+// resultText 純文字辨識結果：每項一行，最後一行為合計（note 不為空時放在最前面），供文字回覆與 Flex altText 使用
+func resultText(locale string, result *imageai.RecognitionResult, note string) string {
+	lines := make([]string, 0, len(result.Items)+4)
+	if note != "" {
+		lines = append(lines, note, "")
+	}
+	for _, item := range result.Items {
+		lines = append(lines, i18n.T(locale, "result.item", itemName(locale, item), item.Kcal))
+	}
+	total := result.Total()
+	lines = append(lines, "", i18n.T(locale, "result.total_line", total.Kcal, total.Protein, total.Fat, total.Carbs))
+	return strings.Join(lines, "\n")
+}
+
+// itemName 食物名稱加上份量，例如「白飯（1碗）」
+func itemName(locale string, item imageai.FoodItem) string {
+	if item.Portion == "" {
+		return item.Name
+	}
+	return i18n.T(locale, "result.portion", item.Name, item.Portion)
+}
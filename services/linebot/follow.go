@@ -0,0 +1,79 @@
+package linebot
+
+import (
+	"log"
+	"time"
+
+	"project/models"
+	"project/services/i18n"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+// handleFollow 處理加入好友（含解除封鎖）：取得個人資料、寫入 users 表並回覆歡迎訊息。
+func (s *LineBotService) handleFollow(event *linebot.Event) {
+	userID := event.Source.UserID
+	if userID == "" {
+		log.Printf("follow 事件缺少 userID")
+		return
+	}
+
+	now := time.Now()
+	user := &models.User{
+		LineUserID: userID,
+		Active:     true,
+		FollowedAt: &now,
+	}
+	locale := i18n.DefaultLocale
+	profile, err := s.bot.GetProfile(userID).Do()
+	if err != nil {
+		logsvc.Error("取得個人資料失敗 userID=%s err=%s", userID, err.Error())
+	} else {
+		user.DisplayName = profile.DisplayName
+		user.PictureURL = profile.PictureURL
+		user.StatusMessage = profile.StatusMessage
+		user.Language = profile.Language
+		locale = localeOf(user)
+	}
+
+	if s.db != nil {
+		if err := s.db.UpsertUser(user, profile != nil); err != nil {
+			logsvc.Error("寫入使用者失敗 userID=%s err=%s", userID, err.Error())
+		} else {
+			logsvc.Info("使用者加入好友 userID=%s", userID)
+		}
+	}
+
+	if s.db != nil {
+		// 重新加入時沿用先前自行設定的語系
+		if existing, err := s.db.GetUserByLineID(userID); err == nil {
+			user.Locale = existing.Locale
+			locale = localeOf(user)
+		}
+	}
+	s.locales.Set(userID, locale)
+	s.replyText(event, welcomeText(locale, user.DisplayName))
+}
+
+// handleUnfollow 處理封鎖 / 取消好友：將使用者標記為停用，之後不再推播（unfollow 沒有 reply token）。
+func (s *LineBotService) handleUnfollow(event *linebot.Event) {
+	userID := event.Source.UserID
+	if userID == "" || s.db == nil {
+		return
+	}
+	if err := s.db.DeactivateUser(userID); err != nil {
+		logsvc.Error("停用使用者失敗 userID=%s err=%s", userID, err.Error())
+		return
+	}
+	logsvc.Info("使用者封鎖 userID=%s", userID)
+}
+
+// welcomeText 產生加入好友的歡迎與使用說明（依語系）
+func welcomeText(locale, displayName string) string {
+	greeting := i18n.T(locale, "welcome.greeting")
+	if displayName != "" {
+		greeting = i18n.T(locale, "welcome.greeting_name", displayName)
+	}
+	return greeting + i18n.T(locale, "welcome.body")
+}
@@ -0,0 +1,241 @@
+package linebot
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"project/models"
+	"project/services/cache"
+	"project/services/i18n"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultTriggerWords = "記錄,食物辨識"
+	// armTTL 群組中觸發後，等待該成員上傳圖片的時間
+	armTTL           = 2 * time.Minute
+	leaderboardLimit = 10
+)
+
+// leaderboardCommands 各語系的排行榜指令（比對時不分大小寫）
+var leaderboardCommands = []string{"排行榜", "leaderboard", "ランキング"}
+
+// isLeaderboardCommand 群組指令是否為查詢排行榜
+func isLeaderboardCommand(command string) bool {
+	command = strings.ToLower(strings.TrimSpace(command))
+	for _, cmd := range leaderboardCommands {
+		if command == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// chatID 群組或聊天室 ID；一對一聊天回傳空字串
+func chatID(source *linebot.EventSource) string {
+	if source == nil {
+		return ""
+	}
+	if source.GroupID != "" {
+		return source.GroupID
+	}
+	return source.RoomID
+}
+
+// isGroupChat 事件是否來自群組或多人聊天室
+func isGroupChat(source *linebot.EventSource) bool {
+	return chatID(source) != ""
+}
+
+// triggerWords 群組中喚起 Bot 的關鍵字，可由 GROUP_TRIGGER_WORDS（逗號分隔）設定
+func triggerWords() []string {
+	value := viper.GetString("Group.Trigger.Words")
+	if value == "" {
+		value = defaultTriggerWords
+	}
+	words := make([]string, 0)
+	for _, w := range strings.Split(value, ",") {
+		if w = strings.TrimSpace(w); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// groupState 群組相關的執行期狀態：Bot 自己的 userID（判斷是否被 @）與已觸發、等待上傳圖片的成員
+type groupState struct {
+	mu        sync.Mutex
+	botUserID string
+	armed     *cache.LRU
+}
+
+func newGroupState() *groupState {
+	return &groupState{armed: cache.NewLRU(10000, armTTL)}
+}
+
+// botUserID 取得 Bot 的 userID（第一次使用時向 LINE 查詢，失敗時下次再試）
+func (s *LineBotService) botUserID() string {
+	s.group.mu.Lock()
+	defer s.group.mu.Unlock()
+	if s.group.botUserID == "" {
+		info, err := s.bot.GetBotInfo().Do()
+		if err != nil {
+			logsvc.Error("取得 Bot 資訊失敗 err=%s", err.Error())
+			return ""
+		}
+		s.group.botUserID = info.UserID
+	}
+	return s.group.botUserID
+}
+
+// groupCommand 判斷群組中的文字訊息是否在呼叫 Bot（被 @ 或以觸發關鍵字開頭），
+// 是的話回傳去掉 @ 與關鍵字後的指令文字（可能為空）。
+func (s *LineBotService) groupCommand(message *linebot.TextMessage) (string, bool) {
+	text, mentioned := stripBotMention(message, s.botUserID())
+	text = strings.TrimSpace(text)
+	for _, word := range triggerWords() {
+		if strings.HasPrefix(text, word) {
+			return strings.TrimSpace(strings.TrimPrefix(text, word)), true
+		}
+	}
+	return text, mentioned
+}
+
+// stripBotMention 移除訊息中 @Bot 的文字，回傳處理後的文字與是否有 @Bot。
+// LINE 的 mention index / length 以 UTF-16 code unit 計算。
+func stripBotMention(message *linebot.TextMessage, botUserID string) (string, bool) {
+	if message.Mention == nil || botUserID == "" {
+		return message.Text, false
+	}
+	units := utf16.Encode([]rune(message.Text))
+	remove := make([]bool, len(units))
+	mentioned := false
+	for _, m := range message.Mention.Mentionees {
+		if m == nil || m.UserID != botUserID {
+			continue
+		}
+		mentioned = true
+		for i := m.Index; i < m.Index+m.Length && i < len(units); i++ {
+			remove[i] = true
+		}
+	}
+	if !mentioned {
+		return message.Text, false
+	}
+	kept := make([]uint16, 0, len(units))
+	for i, u := range units {
+		if !remove[i] {
+			kept = append(kept, u)
+		}
+	}
+	return string(utf16.Decode(kept)), true
+}
+
+// armKey 群組中等待上傳圖片的成員 key
+func armKey(source *linebot.EventSource) string {
+	return chatID(source) + ":" + source.UserID
+}
+
+// armImage 記錄此成員已呼叫 Bot，接下來 armTTL 內上傳的圖片會被辨識
+func (s *LineBotService) armImage(source *linebot.EventSource) {
+	s.group.armed.Set(armKey(source), struct{}{})
+}
+
+// consumeArmedImage 此成員是否已呼叫 Bot；是的話清除標記（一次觸發辨識一張圖片）
+func (s *LineBotService) consumeArmedImage(source *linebot.EventSource) bool {
+	key := armKey(source)
+	if _, ok := s.group.armed.Get(key); !ok {
+		return false
+	}
+	s.group.armed.Delete(key)
+	return true
+}
+
+// handleJoin Bot 被加入群組 / 聊天室：記錄群組並回覆使用說明
+func (s *LineBotService) handleJoin(event *linebot.Event) {
+	groupID := chatID(event.Source)
+	if groupID == "" {
+		return
+	}
+	if s.db != nil {
+		now := time.Now()
+		group := &models.Group{GroupID: groupID, Type: string(event.Source.Type), Active: true, JoinedAt: &now}
+		if err := s.db.UpsertGroup(group); err != nil {
+			logsvc.Error("寫入群組失敗 groupID=%s err=%s", groupID, err.Error())
+		}
+	}
+	logsvc.Info("加入群組 groupID=%s", groupID)
+	s.replyT(event, "group.welcome")
+}
+
+// handleLeave Bot 被移出群組 / 聊天室（leave 沒有 reply token）
+func (s *LineBotService) handleLeave(event *linebot.Event) {
+	groupID := chatID(event.Source)
+	if groupID == "" {
+		return
+	}
+	if s.db != nil {
+		if err := s.db.DeactivateGroup(groupID); err != nil {
+			logsvc.Error("停用群組失敗 groupID=%s err=%s", groupID, err.Error())
+		}
+	}
+	logsvc.Info("離開群組 groupID=%s", groupID)
+}
+
+// handleMemberJoined 有新成員加入群組：回覆簡短的使用說明
+func (s *LineBotService) handleMemberJoined(event *linebot.Event) {
+	if event.Joined == nil || len(event.Joined.Members) == 0 {
+		return
+	}
+	s.replyT(event, "group.member_welcome")
+}
+
+// handleLeaderboard 回覆群組今天（以查詢者的時區計算）的記錄排行
+func (s *LineBotService) handleLeaderboard(event *linebot.Event) {
+	if s.db == nil {
+		s.replyT(event, "leaderboard.unavailable")
+		return
+	}
+	groupID := chatID(event.Source)
+	from, to := diaryToday.Range(time.Now(), s.eventLocation(event))
+	entries, err := s.db.GroupLeaderboard(groupID, from, to, leaderboardLimit)
+	if err != nil {
+		logsvc.Error("查詢排行榜失敗 groupID=%s err=%s", groupID, err.Error())
+		s.replyT(event, "diary.query_failed")
+		return
+	}
+	if len(entries) == 0 {
+		s.replyT(event, "leaderboard.empty")
+		return
+	}
+
+	locale := s.eventLocale(event)
+	var b strings.Builder
+	b.WriteString(i18n.T(locale, "leaderboard.title", from.Format("01/02")))
+	for i, entry := range entries {
+		b.WriteString("\n" + i18n.T(locale, "leaderboard.row", i+1, s.memberName(locale, event.Source, entry.LineUserID), entry.Meals, entry.TotalKcal))
+	}
+	s.replyText(event, b.String())
+}
+
+// memberName 取得群組 / 聊天室成員的顯示名稱，失敗時回傳「匿名成員」（依語系）
+func (s *LineBotService) memberName(locale string, source *linebot.EventSource, userID string) string {
+	var (
+		profile *linebot.UserProfileResponse
+		err     error
+	)
+	if source.GroupID != "" {
+		profile, err = s.bot.GetGroupMemberProfile(source.GroupID, userID).Do()
+	} else {
+		profile, err = s.bot.GetRoomMemberProfile(source.RoomID, userID).Do()
+	}
+	if err != nil || profile.DisplayName == "" {
+		return i18n.T(locale, "member.anonymous")
+	}
+	return profile.DisplayName
+}
@@ -0,0 +1,179 @@
+package linebot
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"github.com/spf13/viper"
+)
+
+// defaultImageSetWindow 一組照片最後一張送達後，最多再等多久就以目前收到的照片辨識
+const defaultImageSetWindow = 3 * time.Second
+
+// imageSetBuffer 暫存同一組照片（LINE ImageSet）的圖片，湊齊 Total 張或等待逾時後一次合併辨識。
+// 逾時的辨識不在計時器的 goroutine 執行，而是以 dispatch 排入該來源的 worker 佇列，與其他事件依序處理並受 worker 數量限制。
+type imageSetBuffer struct {
+	mu       sync.Mutex
+	window   time.Duration
+	sets     map[string]*pendingImageSet
+	dispatch func(source *linebot.EventSource, run func()) error
+	flush    func(event *linebot.Event, userID string, contentIDs []string)
+	running  sync.WaitGroup // 已排入佇列、尚未完成的逾時辨識
+}
+
+// pendingImageSet 等待中的一組照片
+type pendingImageSet struct {
+	event    *linebot.Event // 最後收到的事件，回覆時使用其 reply token（最新、最不容易逾期）
+	userID   string
+	total    int
+	images   map[int]string // ImageSet.Index（從 1 起算）→ 內容 ID
+	timer    *time.Timer
+	received int
+}
+
+func newImageSetBuffer(window time.Duration, dispatch func(source *linebot.EventSource, run func()) error, flush func(event *linebot.Event, userID string, contentIDs []string)) *imageSetBuffer {
+	if window <= 0 {
+		window = defaultImageSetWindow
+	}
+	return &imageSetBuffer{
+		window:   window,
+		sets:     make(map[string]*pendingImageSet),
+		dispatch: dispatch,
+		flush:    flush,
+	}
+}
+
+// newImageSetBufferFromEnv 等待時間讀取 LINE_IMAGESET_WINDOW（預設 3s）
+func newImageSetBufferFromEnv(dispatch func(source *linebot.EventSource, run func()) error, flush func(event *linebot.Event, userID string, contentIDs []string)) *imageSetBuffer {
+	return newImageSetBuffer(viper.GetDuration("Line.ImageSet.Window"), dispatch, flush)
+}
+
+// imageSetKey 以 userID 與 ImageSet ID 區分（不同成員在同一群組傳的照片組互不干擾）
+func imageSetKey(source *linebot.EventSource, setID string) string {
+	return source.UserID + ":" + setID
+}
+
+// pending 是否已有同一組照片在等待中（群組中只有第一張需要先呼叫 Bot）
+func (b *imageSetBuffer) pending(source *linebot.EventSource, setID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.sets[imageSetKey(source, setID)]
+	return ok
+}
+
+// add 加入一張照片；湊齊時立即在目前的 worker 中辨識，否則重設等待計時器
+func (b *imageSetBuffer) add(event *linebot.Event, userID string, message *linebot.ImageMessage) {
+	key := imageSetKey(event.Source, message.ImageSet.ID)
+
+	b.mu.Lock()
+	set, ok := b.sets[key]
+	if !ok {
+		set = &pendingImageSet{userID: userID, total: message.ImageSet.Total, images: make(map[int]string)}
+		b.sets[key] = set
+		source := event.Source
+		set.timer = time.AfterFunc(b.window, func() { b.expire(key, source) })
+	}
+	if _, dup := set.images[message.ImageSet.Index]; !dup {
+		set.received++
+	}
+	set.images[message.ImageSet.Index] = message.ID
+	set.event = event
+	complete := set.received >= set.total
+	if complete {
+		set.timer.Stop()
+		delete(b.sets, key)
+	} else {
+		set.timer.Reset(b.window)
+	}
+	b.mu.Unlock()
+
+	if complete {
+		b.flush(set.event, set.userID, set.contentIDs())
+	}
+}
+
+// expire 等待逾時：將辨識排入來源的 worker 佇列；佇列已滿時再等一個等待時間後重試，
+// dispatcher 已關閉（服務關閉中）時保留在 sets 中由 flushAll 處理
+func (b *imageSetBuffer) expire(key string, source *linebot.EventSource) {
+	b.running.Add(1)
+	err := b.dispatch(source, func() {
+		defer b.running.Done()
+		b.flushKey(key)
+	})
+	if err == nil {
+		return
+	}
+	b.running.Done()
+	if errors.Is(err, ErrQueueFull) {
+		logsvc.Warn("照片組等待逾時但事件佇列已滿，稍後重試 key=%s", key)
+		b.mu.Lock()
+		if set, ok := b.sets[key]; ok {
+			set.timer.Reset(b.window)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// flushKey 以目前收到的照片辨識（LINE 可能漏送或使用者中途取消）
+func (b *imageSetBuffer) flushKey(key string) {
+	b.mu.Lock()
+	set, ok := b.sets[key]
+	if ok {
+		delete(b.sets, key)
+	}
+	b.mu.Unlock()
+	if !ok {
+		return
+	}
+	logsvc.Warn("照片組等待逾時 userID=%s 收到 %d/%d 張", set.userID, set.received, set.total)
+	b.flush(set.event, set.userID, set.contentIDs())
+}
+
+// wait 等待已排入佇列的逾時辨識完成；ctx 逾時則回傳 ctx.Err()
+func (b *imageSetBuffer) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		b.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flushAll 立即處理所有等待中的照片組（服務關閉、worker 都已停止後呼叫，避免使用者收不到回覆）
+func (b *imageSetBuffer) flushAll() {
+	b.mu.Lock()
+	keys := make([]string, 0, len(b.sets))
+	for key, set := range b.sets {
+		set.timer.Stop()
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+	for _, key := range keys {
+		b.flushKey(key)
+	}
+}
+
+// contentIDs 依 ImageSet.Index 排序的內容 ID
+func (p *pendingImageSet) contentIDs() []string {
+	indexes := make([]int, 0, len(p.images))
+	for index := range p.images {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	ids := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		ids = append(ids, p.images[index])
+	}
+	return ids
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -11,40 +12,118 @@ import (
 	"strings"
 	"time"
 
+	"project/models"
+	"project/services/cache"
 	"project/services/imageai"
 	logsvc "project/services/log"
-	"project/services/s3"
+	"project/services/redis"
+	"project/services/speech"
+	"project/services/storage"
 
 	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"github.com/spf13/viper"
 )
 
 // LineBotService 封裝 LINE Bot 客戶端與事件處理邏輯
 type LineBotService struct {
 	bot         *linebot.Client
-	s3Uploader  *s3.Uploader
+	storage     storage.Storage    // 可為 nil（儲存空間未設定）
+	recognizer  imageai.Recognizer // 可為 nil（辨識服務未設定）
+	contexts    imageai.ContextStore
+	transcriber speech.Transcriber // 可為 nil（語音記錄未設定）
+	db          *models.DBManager  // 可為 nil（資料庫未設定時不保存使用者資料）
+	redis       *redis.Client      // 可能不可用（IsAvailable 為 false），各功能需自行降級
+	dedup       *eventDeduper
+	dispatcher  *eventDispatcher
+	group       *groupState
+	imageSets   *imageSetBuffer
+	locales     *cache.LRU // userID → 回覆語系
+	timezones   *cache.LRU // userID → 時區（*time.Location）
+	quota       *quotaLimiter
 }
 
-// NewLineBotService 建立 LINE Bot 服務（直接傳入憑證與 S3 Uploader）
-func NewLineBotService(channelSecret, channelToken string, s3Uploader *s3.Uploader) (*LineBotService, error) {
+// Dependencies LineBotService 的外部相依，由呼叫端注入（測試時可換成假的實作或指向本機 mock server）；
+// 欄位為 nil 時對應功能會降級或停用。
+type Dependencies struct {
+	Storage     storage.Storage
+	DB          *models.DBManager
+	Redis       *redis.Client
+	Recognizer  imageai.Recognizer
+	Transcriber speech.Transcriber
+	Contexts    imageai.ContextStore // nil 時使用記憶體 store
+}
+
+// NewLineBotService 建立 LINE Bot 服務（直接傳入憑證與外部相依）
+func NewLineBotService(channelSecret, channelToken string, deps Dependencies) (*LineBotService, error) {
 	bot, err := linebot.New(channelSecret, channelToken)
 	if err != nil {
 		return nil, err
 	}
-	return &LineBotService{bot: bot, s3Uploader: s3Uploader}, nil
+	if deps.Contexts == nil {
+		deps.Contexts = imageai.NewMemoryContextStore(0, 0)
+	}
+	s := &LineBotService{
+		bot:         bot,
+		contexts:    deps.Contexts,
+		storage:     deps.Storage,
+		recognizer:  deps.Recognizer,
+		transcriber: deps.Transcriber,
+		db:          deps.DB,
+		redis:       deps.Redis,
+		dedup:       newEventDeduper(deps.Redis),
+		group:       newGroupState(),
+		locales:     cache.NewLRU(localeCacheSize, localeCacheTTL),
+		timezones:   cache.NewLRU(timezoneCacheSize, timezoneCacheTTL),
+		quota:       newQuotaLimiterFromEnv(deps.Redis),
+	}
+	s.dispatcher = newEventDispatcherFromEnv(s.handleEvent)
+	s.imageSets = newImageSetBufferFromEnv(s.dispatcher.DispatchFunc, s.recognizeAndReply)
+	return s, nil
 }
 
-// NewLineBotServiceFromEnv 從環境變數建立 LINE Bot 服務（含 S3 Uploader）
+// NewLineBotServiceFromEnv 從環境變數建立 LINE Bot 服務（含儲存空間、資料庫、Redis、辨識與語音轉文字）
 func NewLineBotServiceFromEnv() (*LineBotService, error) {
 	channelSecret := os.Getenv("LINE_CHANNEL_SECRET")
 	channelToken := os.Getenv("LINE_CHANNEL_ACCESS_TOKEN")
 	if channelSecret == "" || channelToken == "" {
 		return nil, errors.New("LINE_CHANNEL_SECRET 與 LINE_CHANNEL_ACCESS_TOKEN 必須設定")
 	}
-	var s3Uploader *s3.Uploader
-	if u, err := s3.NewUploaderFromEnv(); err == nil {
-		s3Uploader = u
+	deps := Dependencies{
+		DB:    newDBFromEnv(),
+		Redis: redis.NewRedisClient(),
+	}
+	deps.Contexts = imageai.NewContextStoreFromEnv(deps.Redis)
+	if st, err := storage.NewFromEnv(); err == nil {
+		deps.Storage = st
+	} else {
+		logsvc.Warn("儲存空間未設定: %v，將無法儲存圖片", err)
+	}
+	if r, err := imageai.NewRecognizerFromEnv(); err == nil {
+		// 同一張圖片（縮圖後內容相同）直接回傳快取結果，不再重複呼叫模型
+		deps.Recognizer = imageai.NewCachingRecognizer(r, deps.Redis,
+			viper.GetDuration("Recognition.Cache.TTL"), viper.GetInt("Recognition.Cache.Size"))
+	} else {
+		logsvc.Warn("辨識服務未設定: %v，將無法辨識食物", err)
+	}
+	if t, err := speech.NewTranscriberFromEnv(); err == nil {
+		deps.Transcriber = t
+	} else {
+		logsvc.Warn("語音轉文字未設定: %v，將不支援語音記錄", err)
 	}
-	return NewLineBotService(channelSecret, channelToken, s3Uploader)
+	return NewLineBotService(channelSecret, channelToken, deps)
+}
+
+// newDBFromEnv 取得共用的資料庫連線並建立資料表，失敗時回傳 nil（優雅降級，不影響辨識功能）
+func newDBFromEnv() *models.DBManager {
+	db, err := models.SharedDB()
+	if err != nil {
+		logsvc.Warn("資料庫連線失敗: %v，將不保存使用者資料", err)
+		return nil
+	}
+	if err := db.Migrate(); err != nil {
+		logsvc.Error("資料表建立失敗 err=%s", err.Error())
+	}
+	return db
 }
 
 // ParseRequest 解析 Webhook 請求並驗證簽章，回傳事件列表
@@ -52,22 +131,63 @@ func (s *LineBotService) ParseRequest(req *http.Request) ([]*linebot.Event, erro
 	return s.bot.ParseRequest(req)
 }
 
-// HandleEvents 處理一組 Webhook 事件（可擴充不同事件類型）
-func (s *LineBotService) HandleEvents(events []*linebot.Event) {
+// HandleEvents 將一組 Webhook 事件放入 worker 佇列（可擴充不同事件類型）
+// 以 webhookEventId 去重，LINE 重送已處理過的事件時直接略過。
+// 佇列已滿或服務關閉中時回傳錯誤，未被接受的事件會移除去重紀錄，讓 LINE 重送時能再處理。
+func (s *LineBotService) HandleEvents(events []*linebot.Event) error {
+	var dispatchErr error
 	for _, event := range events {
-		go s.handleEvent(event)
+		if !s.dedup.FirstSeen(event.WebhookEventID) {
+			log.Printf("略過重複的事件: %s (redelivery=%v)", event.WebhookEventID, event.DeliveryContext.IsRedelivery)
+			continue
+		}
+		if err := s.dispatcher.Dispatch(event); err != nil {
+			logsvc.Warn("事件未能排入佇列 eventID=%s err=%s", event.WebhookEventID, err.Error())
+			s.dedup.Forget(event.WebhookEventID)
+			dispatchErr = err
+		}
+	}
+	return dispatchErr
+}
+
+// QueueStats 事件佇列的即時指標（佇列深度、處理中數量等）
+func (s *LineBotService) QueueStats() DispatcherStats {
+	return s.dispatcher.Stats()
+}
+
+// Shutdown 停止接受新事件並等待佇列中的事件（含已排入的照片組辨識）處理完畢，再處理尚在等待的照片組（收到 SIGTERM 時呼叫）
+func (s *LineBotService) Shutdown(ctx context.Context) error {
+	err := s.dispatcher.Shutdown(ctx)
+	s.imageSets.flushAll()
+	if waitErr := s.imageSets.wait(ctx); err == nil {
+		err = waitErr
 	}
+	return err
 }
 
 func (s *LineBotService) handleEvent(event *linebot.Event) {
+	// 重送的事件 reply token 多半已過期：清除 reply token 後照常處理，回覆改以 push 送出（見 reply）。
+	// 通過 dedup 的重送事件代表先前沒有處理完成（例如排入佇列失敗），不能略過，否則照片、儲存等操作會遺失。
+	if event.DeliveryContext.IsRedelivery && replyTokenExpired(event, time.Now()) {
+		logsvc.Info("重送事件的 reply token 已過期，改以 push 回覆 eventID=%s type=%s", event.WebhookEventID, event.Type)
+		event.ReplyToken = ""
+	}
+
 	switch event.Type {
 	case linebot.EventTypeMessage:
 		s.handleMessage(event)
-	// 可在此擴充其他事件類型
-	// case linebot.EventTypeFollow:
-	// 	s.handleFollow(event)
-	// case linebot.EventTypeUnfollow:
-	// 	s.handleUnfollow(event)
+	case linebot.EventTypeFollow:
+		s.handleFollow(event)
+	case linebot.EventTypeUnfollow:
+		s.handleUnfollow(event)
+	case linebot.EventTypePostback:
+		s.handlePostback(event)
+	case linebot.EventTypeJoin:
+		s.handleJoin(event)
+	case linebot.EventTypeLeave:
+		s.handleLeave(event)
+	case linebot.EventTypeMemberJoined:
+		s.handleMemberJoined(event)
 	default:
 		log.Printf("未處理的事件類型: %s", event.Type)
 	}
@@ -79,15 +199,21 @@ func (s *LineBotService) handleMessage(event *linebot.Event) {
 		s.handleTextMessage(event, message)
 	case *linebot.ImageMessage:
 		s.handleImageMessage(event, message)
+	case *linebot.AudioMessage:
+		s.handleAudioMessage(event, message)
 	// case *linebot.StickerMessage:
 	// 	s.handleStickerMessage(event, message)
 	default:
 		log.Printf("未處理的訊息類型: %T", event.Message)
-		replyText(s.bot, event.ReplyToken, "請上傳食物圖片，我會幫你辨識圖片中的食物。")
+		// 群組中不回應一般聊天內容
+		if !isGroupChat(event.Source) {
+			s.replyT(event, "upload.prompt")
+		}
 	}
 }
 
 // handleTextMessage 處理文字訊息：儲存關鍵字觸發上傳；否則引導上傳圖片。
+// 群組 / 聊天室中只有被 @ 或以觸發關鍵字開頭時才處理，單純呼叫（沒有指令）時等待該成員上傳圖片。
 func (s *LineBotService) handleTextMessage(event *linebot.Event, message *linebot.TextMessage) {
 	userID := event.Source.UserID
 	if userID == "" {
@@ -96,108 +222,363 @@ func (s *LineBotService) handleTextMessage(event *linebot.Event, message *linebo
 	log.Printf("收到訊息: %s (來自: %s)", message.Text, userID)
 
 	text := message.Text
-	if strings.Contains(strings.ToLower(text), "save") || strings.Contains(text, "儲存") {
-		s.handleSaveImage(event, userID)
+	if isGroupChat(event.Source) {
+		command, called := s.groupCommand(message)
+		if !called {
+			return
+		}
+		switch command {
+		case "":
+			s.armImage(event.Source)
+			s.replyT(event, "upload.prompt_short")
+			return
+		}
+		if isLeaderboardCommand(command) {
+			s.handleLeaderboard(event)
+			return
+		}
+		text = command
+	}
+
+	if arg, ok := parseLocaleCommand(text); ok {
+		s.handleLocaleCommand(event, userID, arg)
+		return
+	}
+	if arg, ok := parseTimezoneCommand(text); ok {
+		s.handleTimezoneCommand(event, userID, arg)
+		return
+	}
+	if isDeleteLastCommand(text) {
+		s.handleDeleteLastCommand(event, userID)
+		return
+	}
+	if isSaveCommand(text) {
+		s.handleSaveImage(event, userID, "")
+		return
+	}
+	if period, ok := parseDiaryPeriod(text); ok {
+		s.handleDiaryQuery(event, userID, period)
 		return
 	}
 
-	replyText(s.bot, event.ReplyToken, "請上傳食物圖片，我會幫你辨識圖片中的食物。")
+	s.replyT(event, "upload.prompt")
 }
 
-// handleSaveImage 處理儲存指令：若 context 有上一則成功辨識的圖片則上傳 S3，否則引導先上傳。
-func (s *LineBotService) handleSaveImage(event *linebot.Event, userID string) {
-	imgCtx := imageai.Get(userID)
+// handleSaveImage 處理儲存指令：若 context 有成功辨識的圖片則上傳至儲存空間並寫入飲食日記，否則引導先上傳。
+// contentID 為空（文字「儲存」）時儲存最新一筆，否則儲存卡片按鈕所指的那一筆；語音記錄沒有圖片，直接寫入飲食日記。
+func (s *LineBotService) handleSaveImage(event *linebot.Event, userID, contentID string) {
+	imgCtx := s.contexts.Find(userID, chatID(event.Source), contentID)
 	if imgCtx == nil {
-		replyText(s.bot, event.ReplyToken, "請先上傳食物圖片再儲存")
+		s.replyT(event, "save.no_context")
 		return
 	}
-	if s.s3Uploader == nil {
-		replyText(s.bot, event.ReplyToken, "上傳失敗（S3 未設定）")
+	if imgCtx.Source == imageai.SourceAudio {
+		s.saveMeal(event, imgCtx, nil)
+		return
+	}
+	if s.storage == nil {
+		s.replyT(event, "save.storage_unconfigured")
 		return
 	}
 
-	contentResp, err := s.bot.GetMessageContent(imgCtx.ContentID).Do()
+	// 一組照片逐張上傳，任一張失敗即中止（context 保留，使用者可再按一次儲存）
+	contentIDs := imgCtx.AllContentIDs()
+	keys := make([]string, 0, len(contentIDs))
+	for _, contentID := range contentIDs {
+		key, err := s.uploadContent(userID, contentID)
+		if err != nil {
+			logsvc.Error("上傳失敗 userID=%s contentID=%s err=%s", userID, contentID, err.Error())
+			s.replyT(event, "save.upload_failed")
+			return
+		}
+		logsvc.Info("上傳成功 userID=%s key=%s", userID, key)
+		keys = append(keys, key)
+	}
+	s.saveMeal(event, imgCtx, keys)
+}
+
+// imageVariants 儲存時由原圖產生的版本與長邊上限（px）
+var imageVariants = []struct {
+	name    string
+	maxSide int
+}{
+	{storage.VariantThumb, 240},
+	{storage.VariantDisplay, 1080},
+}
+
+// uploadContent 下載 LINE 訊息內容並寫入儲存空間，回傳原圖的物件 key。
+// key 由內容的 SHA-256 與實際圖片格式的副檔名組成，相同內容已存在時不重複上傳；
+// metadata 記錄原始 LINE 訊息 ID 與上傳時間。原圖寫入後另外產生縮圖與顯示用版本（見 storage.VariantKey）。
+func (s *LineBotService) uploadContent(userID, contentID string) (string, error) {
+	contentResp, err := s.bot.GetMessageContent(contentID).Do()
 	if err != nil {
-		logsvc.Error("上傳失敗 userID=%s 取得圖片失敗 err=%s", userID, err.Error())
-		replyText(s.bot, event.ReplyToken, "上傳失敗")
-		return
+		return "", fmt.Errorf("取得圖片失敗: %w", err)
 	}
 	defer contentResp.Content.Close()
+	data, err := io.ReadAll(contentResp.Content)
+	if err != nil {
+		return "", fmt.Errorf("讀取圖片失敗: %w", err)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	contentType := contentResp.ContentType
-	if contentType == "" {
-		contentType = "image/jpeg"
+	contentType := storage.DetectImageType(data, contentResp.ContentType)
+	key := storage.ImageKey(userID, data, contentType)
+	metadata := map[string]string{
+		storage.MetaLineMessageID: contentID,
+		storage.MetaUploadedAt:    time.Now().Format(time.RFC3339),
 	}
+	if err := s.putIfMissing(ctx, key, data, contentType, metadata); err != nil {
+		return "", fmt.Errorf("儲存圖片失敗: %w", err)
+	}
+	s.uploadVariants(ctx, key, data, metadata)
+	return key, nil
+}
 
-	_, err = s.s3Uploader.Upload(ctx, userID, contentResp.Content, contentType)
+// putIfMissing 物件不存在時才寫入；Head 失敗（非 ErrNotFound）時仍直接寫入
+func (s *LineBotService) putIfMissing(ctx context.Context, key string, data []byte, contentType string, metadata map[string]string) error {
+	if _, err := s.storage.Head(ctx, key); err == nil {
+		logsvc.Info("圖片已存在，略過上傳 key=%s", key)
+		return nil
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		logsvc.Warn("檢查圖片是否已存在失敗，直接上傳 key=%s err=%s", key, err.Error())
+	}
+	return s.storage.Put(ctx, key, bytes.NewReader(data), storage.PutOptions{ContentType: contentType, Metadata: metadata})
+}
+
+// uploadVariants 產生並上傳原圖缺少的版本；失敗（例如 HEIC 無法解碼）只記錄 log，不影響儲存，
+// 取圖時缺少的版本會改用原圖
+func (s *LineBotService) uploadVariants(ctx context.Context, key string, data []byte, metadata map[string]string) {
+	var names []string
+	var sizes []int
+	for _, v := range imageVariants {
+		if _, err := s.storage.Head(ctx, storage.VariantKey(key, v.name)); err == nil {
+			continue
+		}
+		names = append(names, v.name)
+		sizes = append(sizes, v.maxSide)
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	variants, err := imageai.ResizeVariants(data, sizes...)
 	if err != nil {
-		logsvc.Error("上傳失敗 userID=%s S3上傳失敗 err=%s", userID, err.Error())
-		replyText(s.bot, event.ReplyToken, "上傳失敗")
+		logsvc.Warn("產生圖片縮圖失敗 key=%s err=%s", key, err.Error())
 		return
 	}
+	for i, variant := range variants {
+		variantKey := storage.VariantKey(key, names[i])
+		err := s.storage.Put(ctx, variantKey, bytes.NewReader(variant), storage.PutOptions{ContentType: "image/jpeg", Metadata: metadata})
+		if err != nil {
+			logsvc.Warn("上傳圖片縮圖失敗 key=%s err=%s", variantKey, err.Error())
+		}
+	}
+}
 
-	logsvc.Info("上傳成功 userID=%s", userID)
-	replyText(s.bot, event.ReplyToken, "上傳成功")
+// saveMeal 將 context 寫入飲食日記（s3Keys 為空表示沒有圖片，例如語音記錄），成功後清除 context 避免重複儲存
+func (s *LineBotService) saveMeal(event *linebot.Event, imgCtx *imageai.UserImageContext, s3Keys []string) {
+	if s.db == nil {
+		if len(s3Keys) > 0 {
+			s.replyT(event, "save.uploaded")
+		} else {
+			s.replyT(event, "diary.unavailable")
+		}
+		return
+	}
+	if err := s.db.CreateMeal(newMeal(imgCtx, s3Keys, s.userLocation(imgCtx.UserID))); err != nil {
+		logsvc.Error("寫入飲食日記失敗 userID=%s keys=%v err=%s", imgCtx.UserID, s3Keys, err.Error())
+		s.replyT(event, "save.db_failed")
+		return
+	}
+	s.contexts.Delete(imgCtx.UserID, imgCtx.GroupID, imgCtx.ContentID)
+	s.replyT(event, "save.saved")
 }
 
 // handleImageMessage 處理圖片訊息：下載、縮放、辨識食物、回覆，成功時寫入 context。
+// 一次傳送多張（ImageSet）時先暫存，湊齊或等待逾時後合併辨識；
+// 群組 / 聊天室中只辨識剛呼叫過 Bot 的成員所上傳的圖片（同一組照片只需呼叫一次）。
 func (s *LineBotService) handleImageMessage(event *linebot.Event, message *linebot.ImageMessage) {
 	userID := event.Source.UserID
 	if userID == "" {
 		userID = "unknown"
 	}
-
-	contentResp, err := s.bot.GetMessageContent(message.ID).Do()
-	if err != nil {
-		logsvc.Error("辨識失敗 userID=%s 取得圖片失敗 err=%s", userID, err.Error())
-		replyText(s.bot, event.ReplyToken, "無法取得圖片，請再試一次")
+	if message.ImageSet != nil && message.ImageSet.Total > 1 {
+		if isGroupChat(event.Source) && !s.imageSets.pending(event.Source, message.ImageSet.ID) && !s.consumeArmedImage(event.Source) {
+			return
+		}
+		s.imageSets.add(event, userID, message)
 		return
 	}
-	defer contentResp.Content.Close()
-
-	imgBytes, err := io.ReadAll(contentResp.Content)
-	if err != nil {
-		logsvc.Error("辨識失敗 userID=%s 讀取圖片失敗 err=%s", userID, err.Error())
-		replyText(s.bot, event.ReplyToken, "無法取得圖片，請再試一次")
+	if isGroupChat(event.Source) && !s.consumeArmedImage(event.Source) {
 		return
 	}
+	s.recognizeAndReply(event, userID, []string{message.ID})
+}
 
-	resized, _, err := imageai.Resize(bytes.NewReader(imgBytes))
-	if err != nil {
-		logsvc.Error("辨識失敗 userID=%s 圖片縮放失敗 err=%s", userID, err.Error())
-		replyText(s.bot, event.ReplyToken, "圖片格式有誤，請重傳")
+// recognizeAndReply 依 LINE 訊息內容 ID 下載圖片、縮放、辨識食物並回覆（附快速回覆按鈕），成功時寫入 context。
+// 多張圖片（一組照片）以一次請求合併辨識。圖片訊息與照片組共用。
+func (s *LineBotService) recognizeAndReply(event *linebot.Event, userID string, contentIDs []string) {
+	s.recognizeAndReplyContext(context.Background(), event, userID, contentIDs)
+}
+
+// retryRecognizeAndReply 「重新辨識」postback：略過辨識快取重新呼叫模型，新結果會取代快取
+func (s *LineBotService) retryRecognizeAndReply(event *linebot.Event, userID string, contentIDs []string) {
+	s.recognizeAndReplyContext(imageai.WithNoCache(context.Background()), event, userID, contentIDs)
+}
+
+// recognizeAndReplyContext 以 base 為基礎 context 執行 recognizeAndReply
+func (s *LineBotService) recognizeAndReplyContext(base context.Context, event *linebot.Event, userID string, contentIDs []string) {
+	if s.recognizer == nil {
+		s.replyT(event, "recognize.unavailable")
+		return
+	}
+	if !s.checkQuota(event, userID) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	images := make([][]byte, 0, len(contentIDs))
+	for _, contentID := range contentIDs {
+		resized, err := s.downloadResized(contentID)
+		if err != nil {
+			logsvc.Error("辨識失敗 userID=%s contentID=%s err=%s", userID, contentID, err.Error())
+			switch {
+			case errors.Is(err, imageai.ErrHEICUnsupported):
+				s.replyT(event, "recognize.heic")
+			case errors.Is(err, errImageDecode):
+				s.replyT(event, "recognize.bad_format")
+			default:
+				s.replyT(event, "recognize.download_failed")
+			}
+			return
+		}
+		images = append(images, resized)
+	}
+
+	ctx, cancel := context.WithTimeout(imageai.WithLocale(base, s.eventLocale(event)), 30*time.Second)
 	defer cancel()
 
-	foods, success, err := imageai.RecognizeFoodFromBytes(ctx, resized)
+	var result *imageai.RecognitionResult
+	var err error
+	start := time.Now()
+	if len(images) == 1 {
+		result, err = s.recognizer.RecognizeImage(ctx, images[0])
+	} else {
+		result, err = s.recognizer.RecognizeImages(ctx, images)
+	}
 	if err != nil {
 		logsvc.Error("辨識失敗 userID=%s API辨識失敗 err=%s", userID, err.Error())
-		replyText(s.bot, event.ReplyToken, "辨識失敗，請稍後再試")
+		s.replyT(event, recognitionErrorKey(err))
 		return
 	}
-	if !success || foods == "" {
-		foods = "無法辨識圖片中的食物"
-	}
+	s.recordUsage(event, userID, imageai.SourceImage, len(images), result, time.Since(start))
 
-	if _, err := s.bot.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(foods)).Do(); err != nil {
+	switch {
+	case result.Unclear():
+		s.replyT(event, "recognize.unclear")
+		return
+	case !result.HasFood():
+		s.replyT(event, "recognize.no_food")
+		return
+	}
+	imgCtx := &imageai.UserImageContext{
+		UserID:     userID,
+		GroupID:    chatID(event.Source),
+		Source:     imageai.SourceImage,
+		ContentID:  contentIDs[0],
+		ReplyToken: event.ReplyToken,
+		Result:     result,
+	}
+	if len(contentIDs) > 1 {
+		imgCtx.ContentIDs = contentIDs
+		imgCtx.Note = s.t(event, "recognize.image_count", len(contentIDs))
+	}
+	if err := s.replyFoodResult(event, imgCtx); err != nil {
 		logsvc.Error("辨識失敗 userID=%s 回覆訊息失敗 err=%s", userID, err.Error())
 		return
 	}
 
-	if success && foods != "無法辨識圖片中的食物" {
-		logsvc.Info("辨識成功 userID=%s", userID)
-		imageai.Set(userID, message.ID, event.ReplyToken)
+	logsvc.Info("辨識成功 userID=%s images=%d cacheHit=%t", userID, len(contentIDs), result.CacheHit)
+	s.contexts.Set(imgCtx)
+}
+
+// errImageDecode 圖片無法解碼（格式不支援或檔案損毀）
+var errImageDecode = errors.New("圖片格式有誤")
+
+// downloadResized 下載 LINE 圖片並縮放為辨識用的 JPEG
+func (s *LineBotService) downloadResized(contentID string) ([]byte, error) {
+	contentResp, err := s.bot.GetMessageContent(contentID).Do()
+	if err != nil {
+		return nil, fmt.Errorf("取得圖片失敗: %w", err)
+	}
+	defer contentResp.Content.Close()
+
+	imgBytes, err := io.ReadAll(contentResp.Content)
+	if err != nil {
+		return nil, fmt.Errorf("讀取圖片失敗: %w", err)
+	}
+	resized, _, err := imageai.Resize(bytes.NewReader(imgBytes))
+	if err != nil {
+		if errors.Is(err, imageai.ErrHEICUnsupported) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", errImageDecode, err)
+	}
+	return resized, nil
+}
+
+// recognitionErrorKey 依辨識錯誤分類回覆使用者對應的訊息 key
+func recognitionErrorKey(err error) string {
+	switch imageai.ErrorKindOf(err) {
+	case imageai.ErrorKindQuota:
+		return "error.quota"
+	case imageai.ErrorKindRateLimit:
+		return "error.rate_limit"
+	case imageai.ErrorKindTimeout:
+		return "error.timeout"
+	case imageai.ErrorKindBadRequest:
+		return "error.bad_request"
+	default:
+		return "error.failed"
+	}
+}
+
+// saveCommands 儲存指令（文字中包含任一即可）
+var saveCommands = []string{"save", "儲存", "保存"}
+
+// isSaveCommand 是否為儲存指令
+func isSaveCommand(text string) bool {
+	lower := strings.ToLower(text)
+	for _, cmd := range saveCommands {
+		if strings.Contains(lower, cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// reply 以 reply token 回覆事件；reply token 已清除（例如逾時的重送事件）時改以 push 傳給來源的聊天室或使用者，
+// 沒有可傳送的對象（例如已封鎖）時不送出
+func (s *LineBotService) reply(event *linebot.Event, messages ...linebot.SendingMessage) error {
+	if event.ReplyToken != "" {
+		_, err := s.bot.ReplyMessage(event.ReplyToken, messages...).Do()
+		return err
+	}
+	to := chatID(event.Source)
+	if to == "" && event.Source != nil {
+		to = event.Source.UserID
+	}
+	if to == "" || event.Type == linebot.EventTypeUnfollow || event.Type == linebot.EventTypeLeave {
+		return nil
 	}
+	_, err := s.bot.PushMessage(to, messages...).Do()
+	return err
 }
 
-func replyText(bot *linebot.Client, token, text string) {
-	if _, err := bot.ReplyMessage(token, linebot.NewTextMessage(text)).Do(); err != nil {
+// replyText 回覆純文字訊息（見 reply）
+func (s *LineBotService) replyText(event *linebot.Event, text string) {
+	if err := s.reply(event, linebot.NewTextMessage(text)); err != nil {
 		log.Printf("回覆訊息失敗: %v", err)
 	}
 }
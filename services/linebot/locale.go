@@ -0,0 +1,107 @@
+package linebot
+
+import (
+	"strings"
+	"time"
+
+	"project/models"
+	"project/services/i18n"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+const (
+	localeCacheSize = 10000
+	localeCacheTTL  = time.Hour
+)
+
+// localeCommands 切換回覆語言的指令前綴（例如「語言 en」「language ja」「言語 zh-TW」）
+var localeCommands = []string{"語言", "language", "言語"}
+
+// localeOf 使用者的回覆語系：自行設定的 Locale 優先，其次為 LINE 個人資料的語言，皆無法對應時使用預設語系
+func localeOf(user *models.User) string {
+	if locale := i18n.Normalize(user.Locale); locale != "" {
+		return locale
+	}
+	if locale := i18n.Normalize(user.Language); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale
+}
+
+// userLocale 取得使用者的回覆語系（快取 1 小時）：先查 users 表，沒有紀錄時改查 LINE 個人資料
+func (s *LineBotService) userLocale(userID string) string {
+	if userID == "" || userID == "unknown" {
+		return i18n.DefaultLocale
+	}
+	if v, ok := s.locales.Get(userID); ok {
+		return v.(string)
+	}
+
+	locale := ""
+	if s.db != nil {
+		if user, err := s.db.GetUserByLineID(userID); err == nil {
+			locale = localeOf(user)
+		}
+	}
+	if locale == "" {
+		locale = i18n.DefaultLocale
+		if profile, err := s.bot.GetProfile(userID).Do(); err == nil {
+			if l := i18n.Normalize(profile.Language); l != "" {
+				locale = l
+			}
+		}
+	}
+	s.locales.Set(userID, locale)
+	return locale
+}
+
+// eventLocale 事件發送者的回覆語系（加入群組等沒有發送者的事件使用預設語系）
+func (s *LineBotService) eventLocale(event *linebot.Event) string {
+	return s.userLocale(event.Source.UserID)
+}
+
+// t 以事件發送者的語系取得訊息
+func (s *LineBotService) t(event *linebot.Event, key string, args ...any) string {
+	return i18n.T(s.eventLocale(event), key, args...)
+}
+
+// replyT 以事件發送者的語系回覆訊息
+func (s *LineBotService) replyT(event *linebot.Event, key string, args ...any) {
+	s.replyText(event, s.t(event, key, args...))
+}
+
+// parseLocaleCommand 判斷是否為切換語言指令，回傳指令後的參數（可能為空）
+func parseLocaleCommand(text string) (string, bool) {
+	return parseCommandArg(text, localeCommands)
+}
+
+// parseCommandArg 判斷文字是否以任一指令開頭（不分大小寫），回傳指令後保留原大小寫的參數
+func parseCommandArg(text string, commands []string) (string, bool) {
+	text = strings.TrimSpace(text)
+	lower := strings.ToLower(text)
+	for _, cmd := range commands {
+		if strings.HasPrefix(lower, cmd) {
+			return strings.TrimSpace(text[len(cmd):]), true
+		}
+	}
+	return "", false
+}
+
+// handleLocaleCommand 切換使用者的回覆語言並寫入 users 表；參數無法對應支援的語系時回覆使用說明
+func (s *LineBotService) handleLocaleCommand(event *linebot.Event, userID, arg string) {
+	locale := i18n.Normalize(arg)
+	if locale == "" {
+		current := s.eventLocale(event)
+		s.replyText(event, i18n.T(current, "locale.usage", i18n.T(current, "locale.name")))
+		return
+	}
+	if s.db != nil {
+		if err := s.db.SetUserLocale(userID, locale); err != nil {
+			logsvc.Error("設定語系失敗 userID=%s err=%s", userID, err.Error())
+		}
+	}
+	s.locales.Set(userID, locale)
+	s.replyText(event, i18n.T(locale, "locale.changed"))
+}
@@ -0,0 +1,91 @@
+package linebot
+
+import (
+	"log"
+	"net/url"
+
+	"project/services/i18n"
+	"project/services/imageai"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+// Postback data 的 action 值（格式：action=save&content=<LINE 內容 ID>）
+const (
+	postbackActionSave       = "save"
+	postbackActionRetry      = "retry"
+	postbackActionDiscard    = "discard"
+	postbackActionClarify    = "clarify"     // 回答低信心食物的澄清問題（item、choice 參數）
+	postbackActionDelete     = "delete"      // 刪除已儲存的照片（meal、key 參數）
+	postbackActionDeleteMeal = "delete_meal" // 從飲食日記刪除一餐（meal 參數；confirm=1 為確認後的刪除）
+)
+
+// postbackData 組出 postback data 字串；contentID 指定操作哪一筆 context（使用者可能對較早的卡片按下按鈕）
+func postbackData(action, contentID string) string {
+	values := url.Values{"action": {action}}
+	if contentID != "" {
+		values.Set("content", contentID)
+	}
+	return values.Encode()
+}
+
+// foodActionQuickReplies 辨識結果下方的快速回覆按鈕：儲存、重新辨識、捨棄
+func foodActionQuickReplies(locale, contentID string) *linebot.QuickReplyItems {
+	return linebot.NewQuickReplyItems(
+		postbackActionButton(locale, "action.save", postbackActionSave, contentID),
+		postbackActionButton(locale, "action.retry", postbackActionRetry, contentID),
+		postbackActionButton(locale, "action.discard", postbackActionDiscard, contentID),
+	)
+}
+
+// postbackActionButton 送出 postback 的快速回覆按鈕（label 與聊天室顯示的文字相同）
+func postbackActionButton(locale, labelKey, action, contentID string) *linebot.QuickReplyButton {
+	label := i18n.T(locale, labelKey)
+	return linebot.NewQuickReplyButton("", linebot.NewPostbackAction(label, postbackData(action, contentID), "", label, "", ""))
+}
+
+// handlePostback 依 postback data 的 action 分派：儲存、以快取的 context 重新辨識、清除 context、刪除已儲存的照片或飲食日記中的一餐。
+func (s *LineBotService) handlePostback(event *linebot.Event) {
+	userID := event.Source.UserID
+	if userID == "" {
+		userID = "unknown"
+	}
+	if event.Postback == nil {
+		return
+	}
+	values, err := url.ParseQuery(event.Postback.Data)
+	if err != nil {
+		log.Printf("postback data 格式錯誤: %s", event.Postback.Data)
+		return
+	}
+
+	contentID := values.Get("content")
+	switch action := values.Get("action"); action {
+	case postbackActionSave:
+		s.handleSaveImage(event, userID, contentID)
+	case postbackActionRetry:
+		imgCtx := s.contexts.Find(userID, chatID(event.Source), contentID)
+		if imgCtx == nil {
+			s.replyT(event, "context.missing")
+			return
+		}
+		if imgCtx.Source == imageai.SourceAudio {
+			s.transcribeAndReply(event, userID, imgCtx.ContentID)
+			return
+		}
+		s.retryRecognizeAndReply(event, userID, imgCtx.AllContentIDs())
+	case postbackActionClarify:
+		s.handleClarify(event, userID, values)
+	case postbackActionDelete:
+		s.handleDeleteImage(event, userID, values)
+	case postbackActionDeleteMeal:
+		s.handleDeleteMeal(event, userID, values)
+	case postbackActionDiscard:
+		s.contexts.Delete(userID, chatID(event.Source), contentID)
+		logsvc.Info("捨棄辨識結果 userID=%s", userID)
+		s.replyT(event, "discard.done")
+	default:
+		log.Printf("未處理的 postback action: %s", action)
+	}
+}
@@ -0,0 +1,161 @@
+package linebot
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"project/services/cache"
+	logsvc "project/services/log"
+	"project/services/redis"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultQuotaPerMinute = 10
+	defaultQuotaPerDay    = 100
+	quotaKeyPrefix        = "imageai:quota:"
+	quotaLRUSize          = 10000
+)
+
+// quotaWindow 限制次數的計算區間
+type quotaWindow string
+
+const (
+	quotaMinute quotaWindow = "minute"
+	quotaDay    quotaWindow = "day"
+)
+
+// quotaDecision 一次額度檢查的結果；Allowed 為 false 時 RetryAt 為可再使用的時間
+type quotaDecision struct {
+	Allowed bool
+	Window  quotaWindow
+	Limit   int64
+	RetryAt time.Time
+}
+
+// quotaLimiter 以固定時間窗計算每位使用者的辨識次數（每分鐘、每天，每天以使用者時區的日界線計算）：
+// 優先使用 Redis 計數（IncrementBy + Expire，多 instance 共用），Redis 不可用或出錯時改用 process 內的計數。
+// 上限為 0 表示不限制；allowlist 中的使用者不受限制。
+type quotaLimiter struct {
+	redis     *redis.Client
+	perMinute int64
+	perDay    int64
+	allow     map[string]bool
+
+	mu  sync.Mutex
+	lru *cache.LRU // key → *quotaCounter
+}
+
+// quotaCounter 記憶體版的計數
+type quotaCounter struct {
+	count   int64
+	resetAt time.Time
+}
+
+func newQuotaLimiter(redisClient *redis.Client, perMinute, perDay int64, allowlist []string) *quotaLimiter {
+	allow := make(map[string]bool, len(allowlist))
+	for _, id := range allowlist {
+		if id = strings.TrimSpace(id); id != "" {
+			allow[id] = true
+		}
+	}
+	return &quotaLimiter{
+		redis:     redisClient,
+		perMinute: perMinute,
+		perDay:    perDay,
+		allow:     allow,
+		lru:       cache.NewLRU(quotaLRUSize, 24*time.Hour),
+	}
+}
+
+// newQuotaLimiterFromEnv 讀取 RECOGNITION_QUOTA_MINUTE（預設 10）、RECOGNITION_QUOTA_DAY（預設 100）
+// 與 RECOGNITION_QUOTA_ALLOWLIST（不受限制的 LINE userID，逗號分隔）
+func newQuotaLimiterFromEnv(redisClient *redis.Client) *quotaLimiter {
+	perMinute, perDay := int64(defaultQuotaPerMinute), int64(defaultQuotaPerDay)
+	if viper.IsSet("Recognition.Quota.Minute") {
+		perMinute = viper.GetInt64("Recognition.Quota.Minute")
+	}
+	if viper.IsSet("Recognition.Quota.Day") {
+		perDay = viper.GetInt64("Recognition.Quota.Day")
+	}
+	allowlist := strings.Split(viper.GetString("Recognition.Quota.Allowlist"), ",")
+	return newQuotaLimiter(redisClient, perMinute, perDay, allowlist)
+}
+
+// Allow 計入一次辨識並判斷是否超過額度（先檢查每分鐘，再檢查每天；每天以使用者時區 loc 的日界線計算）
+func (q *quotaLimiter) Allow(userID string, now time.Time, loc *time.Location) quotaDecision {
+	if q.allow[userID] {
+		return quotaDecision{Allowed: true}
+	}
+	local := now.In(loc)
+	minuteStart := local.Truncate(time.Minute)
+	dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	windows := []struct {
+		window quotaWindow
+		limit  int64
+		key    string
+		reset  time.Time
+	}{
+		{quotaMinute, q.perMinute, minuteStart.Format("200601021504"), minuteStart.Add(time.Minute)},
+		{quotaDay, q.perDay, dayStart.Format("20060102"), dayStart.AddDate(0, 0, 1)},
+	}
+	for _, w := range windows {
+		if w.limit <= 0 {
+			continue
+		}
+		key := quotaKeyPrefix + userID + ":" + string(w.window) + ":" + w.key
+		if count := q.increment(key, w.reset, now); count > w.limit {
+			return quotaDecision{Window: w.window, Limit: w.limit, RetryAt: w.reset}
+		}
+	}
+	return quotaDecision{Allowed: true}
+}
+
+// increment 將計數加一並回傳目前次數；第一次計數時設定到時間窗結束後過期
+func (q *quotaLimiter) increment(key string, resetAt, now time.Time) int64 {
+	if q.redis != nil && q.redis.IsAvailable() {
+		count, err := q.redis.IncrementBy(key, 1)
+		if err == nil {
+			if count == 1 {
+				if err := q.redis.Expire(key, resetAt.Sub(now)+time.Second); err != nil {
+					logsvc.Warn("辨識額度設定過期時間失敗 key=%s err=%s", key, err.Error())
+				}
+			}
+			return count
+		}
+		logsvc.Warn("辨識額度 Redis 計數失敗，改用記憶體 err=%s", err.Error())
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	counter, ok := q.lru.Get(key)
+	if !ok || !now.Before(counter.(*quotaCounter).resetAt) {
+		counter = &quotaCounter{resetAt: resetAt}
+		q.lru.Set(key, counter)
+	}
+	c := counter.(*quotaCounter)
+	c.count++
+	return c.count
+}
+
+// checkQuota 檢查使用者是否還能辨識；超過額度時回覆何時可再試並回傳 false
+func (s *LineBotService) checkQuota(event *linebot.Event, userID string) bool {
+	now := time.Now()
+	loc := s.userLocation(userID)
+	decision := s.quota.Allow(userID, now, loc)
+	if decision.Allowed {
+		return true
+	}
+	logsvc.Warn("辨識額度已用完 userID=%s window=%s limit=%d", userID, decision.Window, decision.Limit)
+	switch decision.Window {
+	case quotaMinute:
+		s.replyT(event, "quota.minute", decision.Limit, int(math.Ceil(decision.RetryAt.Sub(now).Seconds())))
+	default:
+		s.replyT(event, "quota.day", decision.Limit, decision.RetryAt.In(loc).Format("01/02 15:04"))
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package linebot
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"project/models"
+	"project/services/i18n"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultSummaryTime = "21:00"
+	// summaryWindow 超過設定時間多久內仍會補發（服務重啟或排程延遲時）
+	summaryWindow = time.Hour
+	// multicastMaxRecipients LINE multicast 單次最多 500 位收件者
+	multicastMaxRecipients = 500
+)
+
+// summaryMu 避免前一輪推播尚未結束時重複執行
+var summaryMu sync.Mutex
+
+// summaryTime 每日摘要推播時間（使用者時區的 HH:MM），可由 SUMMARY_TIME 環境變數設定
+func summaryTime() (hour, minute int) {
+	value := viper.GetString("Summary.Time")
+	if value == "" {
+		value = defaultSummaryTime
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		logsvc.Warn("SUMMARY_TIME 格式錯誤: %s，改用 %s", value, defaultSummaryTime)
+		t, _ = time.Parse("15:04", defaultSummaryTime)
+	}
+	return t.Hour(), t.Minute()
+}
+
+// summaryRecipient 本輪要推播的對象與內容（Text 為空表示只需提醒）
+type summaryRecipient struct {
+	user   *models.User
+	date   string
+	locale string
+	text   string
+}
+
+// SendDailySummaries 由排程定期呼叫：對已到達推播時間（使用者時區）且當天尚未推播的啟用使用者，
+// 彙整當天儲存的餐點並推播摘要；當天沒有紀錄的使用者以 multicast 批次送出提醒。
+// 推播前會檢查當月剩餘的訊息額度，額度不足時優先送出摘要、其餘留待下次。
+func (s *LineBotService) SendDailySummaries(now time.Time) {
+	if s.db == nil {
+		return
+	}
+	if !summaryMu.TryLock() {
+		logsvc.Warn("每日摘要：上一輪推播尚未結束，略過本次")
+		return
+	}
+	defer summaryMu.Unlock()
+
+	users, err := s.db.ListActiveUsers()
+	if err != nil {
+		logsvc.Error("每日摘要：取得使用者失敗 err=%s", err.Error())
+		return
+	}
+
+	hour, minute := summaryTime()
+	summaries := make([]summaryRecipient, 0)
+	reminders := make([]summaryRecipient, 0)
+	for i := range users {
+		user := &users[i]
+		local := now.In(locationOf(user))
+		dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+		sendAt := dayStart.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		date := local.Format("2006-01-02")
+		if local.Before(sendAt) || local.Sub(sendAt) > summaryWindow || user.LastSummaryDate == date {
+			continue
+		}
+
+		meals, err := s.db.ListMeals(user.LineUserID, dayStart, dayStart.AddDate(0, 0, 1))
+		if err != nil {
+			logsvc.Error("每日摘要：查詢餐點失敗 userID=%s err=%s", user.LineUserID, err.Error())
+			continue
+		}
+		locale := localeOf(user)
+		if len(meals) == 0 {
+			reminders = append(reminders, summaryRecipient{user: user, date: date, locale: locale})
+		} else {
+			summaries = append(summaries, summaryRecipient{user: user, date: date, locale: locale, text: formatDailySummary(locale, meals)})
+		}
+	}
+	// 提醒依語系分批 multicast，同一批的收件者語系相同
+	sort.SliceStable(reminders, func(i, j int) bool { return reminders[i].locale < reminders[j].locale })
+	if len(summaries) == 0 && len(reminders) == 0 {
+		return
+	}
+
+	remaining := s.remainingMessageQuota()
+	sent := 0
+	for _, r := range summaries {
+		if remaining == 0 {
+			logsvc.Warn("每日摘要：訊息額度不足，剩餘 %d 位摘要留待下次", len(summaries)-sent)
+			return
+		}
+		if !s.claimSummary(r) {
+			continue
+		}
+		if _, err := s.bot.PushMessage(r.user.LineUserID, linebot.NewTextMessage(r.text)).Do(); err != nil {
+			logsvc.Error("每日摘要：推播失敗 userID=%s err=%s", r.user.LineUserID, err.Error())
+			s.releaseSummary(r)
+			continue
+		}
+		sent++
+		if remaining > 0 {
+			remaining--
+		}
+	}
+
+	for start, end := 0, 0; start < len(reminders); start = end {
+		locale := reminders[start].locale
+		for end = start + 1; end < len(reminders) && end-start < multicastMaxRecipients && reminders[end].locale == locale; end++ {
+		}
+		batch := reminders[start:end]
+		if remaining >= 0 && int64(len(batch)) > remaining {
+			if remaining == 0 {
+				logsvc.Warn("每日摘要：訊息額度不足，剩餘 %d 位提醒留待下次", len(reminders)-start)
+				return
+			}
+			batch = batch[:remaining]
+		}
+
+		claimed := make([]summaryRecipient, 0, len(batch))
+		to := make([]string, 0, len(batch))
+		for _, r := range batch {
+			if s.claimSummary(r) {
+				claimed = append(claimed, r)
+				to = append(to, r.user.LineUserID)
+			}
+		}
+		if len(to) == 0 {
+			continue
+		}
+		if _, err := s.bot.Multicast(to, linebot.NewTextMessage(i18n.T(locale, "summary.reminder"))).Do(); err != nil {
+			logsvc.Error("每日摘要：multicast 提醒失敗 count=%d err=%s", len(to), err.Error())
+			for _, r := range claimed {
+				s.releaseSummary(r)
+			}
+			continue
+		}
+		sent += len(to)
+		if remaining > 0 {
+			remaining -= int64(len(to))
+		}
+	}
+	logsvc.Info("每日摘要：推播完成 summaries=%d reminders=%d sent=%d", len(summaries), len(reminders), sent)
+}
+
+// remainingMessageQuota 當月剩餘可推播訊息數；-1 表示無上限或無法取得（不限制）
+func (s *LineBotService) remainingMessageQuota() int64 {
+	quota, err := s.bot.GetMessageQuota().Do()
+	if err != nil {
+		logsvc.Warn("每日摘要：取得訊息額度失敗 err=%s", err.Error())
+		return -1
+	}
+	if quota.Type != "limited" {
+		return -1
+	}
+	consumption, err := s.bot.GetMessageConsumption().Do()
+	if err != nil {
+		logsvc.Warn("每日摘要：取得訊息用量失敗 err=%s", err.Error())
+		return -1
+	}
+	if remaining := quota.Value - consumption.TotalUsage; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// claimSummary 在資料庫標記當天已推播（多個 instance 同時執行時只有一個會成功）
+func (s *LineBotService) claimSummary(r summaryRecipient) bool {
+	ok, err := s.db.ClaimDailySummary(r.user.ID, r.date)
+	if err != nil {
+		logsvc.Error("每日摘要：標記推播失敗 userID=%s err=%s", r.user.LineUserID, err.Error())
+		return false
+	}
+	return ok
+}
+
+// releaseSummary 推播失敗時清除標記，下一次排程會重試
+func (s *LineBotService) releaseSummary(r summaryRecipient) {
+	if err := s.db.ReleaseDailySummary(r.user.ID, r.date); err != nil {
+		logsvc.Error("每日摘要：清除推播標記失敗 userID=%s err=%s", r.user.LineUserID, err.Error())
+	}
+}
+
+// formatDailySummary 將當天的餐點整理成摘要文字（依語系）：各餐熱量與當天合計營養素
+func formatDailySummary(locale string, meals []models.Meal) string {
+	var b strings.Builder
+	b.WriteString(i18n.T(locale, "summary.title") + "\n")
+	var kcal, protein, fat, carbs float64
+	for _, meal := range meals {
+		b.WriteString("\n" + i18n.T(locale, "summary.meal", mealName(locale, meal.MealType), meal.TotalKcal))
+		kcal += meal.TotalKcal
+		protein += meal.TotalProtein
+		fat += meal.TotalFat
+		carbs += meal.TotalCarbs
+	}
+	b.WriteString("\n\n" + i18n.T(locale, "summary.total", len(meals), kcal, protein, fat, carbs))
+	return b.String()
+}
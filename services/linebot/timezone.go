@@ -0,0 +1,89 @@
+package linebot
+
+import (
+	"fmt"
+	"time"
+
+	"project/models"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+const (
+	timezoneCacheSize = 10000
+	timezoneCacheTTL  = time.Hour
+)
+
+// timezoneCommands 設定時區的指令前綴（例如「時區 Asia/Tokyo」「timezone Europe/London」）
+var timezoneCommands = []string{"時區", "timezone", "タイムゾーン"}
+
+// loadTimezone 載入 IANA 時區（不接受空值與主機相依的 Local）
+func loadTimezone(name string) (*time.Location, bool) {
+	if name == "" || name == "Local" {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(name)
+	return loc, err == nil
+}
+
+// locationOf 使用者的時區，未設定或無效時使用台北時間
+func locationOf(user *models.User) *time.Location {
+	if loc, ok := loadTimezone(user.Timezone); ok {
+		return loc
+	}
+	return taipeiLocation
+}
+
+// userLocation 取得使用者的時區（快取 1 小時）：餐別、飲食日記的日界線、每日額度與顯示的時間皆依此計算
+func (s *LineBotService) userLocation(userID string) *time.Location {
+	if userID == "" || userID == "unknown" {
+		return taipeiLocation
+	}
+	if v, ok := s.timezones.Get(userID); ok {
+		return v.(*time.Location)
+	}
+	loc := taipeiLocation
+	if s.db != nil {
+		if user, err := s.db.GetUserByLineID(userID); err == nil {
+			loc = locationOf(user)
+		}
+	}
+	s.timezones.Set(userID, loc)
+	return loc
+}
+
+// eventLocation 事件發送者的時區
+func (s *LineBotService) eventLocation(event *linebot.Event) *time.Location {
+	return s.userLocation(event.Source.UserID)
+}
+
+// parseTimezoneCommand 判斷是否為設定時區指令，回傳指令後的參數（可能為空）
+func parseTimezoneCommand(text string) (string, bool) {
+	return parseCommandArg(text, timezoneCommands)
+}
+
+// handleTimezoneCommand 設定使用者的時區並寫入 users 表；沒有參數時回覆使用說明
+func (s *LineBotService) handleTimezoneCommand(event *linebot.Event, userID, arg string) {
+	if arg == "" {
+		s.replyT(event, "timezone.usage", s.userLocation(userID).String())
+		return
+	}
+	loc, ok := loadTimezone(arg)
+	if !ok {
+		s.replyT(event, "timezone.invalid", arg)
+		return
+	}
+	if s.db == nil {
+		s.replyT(event, "timezone.failed")
+		return
+	}
+	if err := s.db.SetUserTimezone(userID, arg); err != nil {
+		logsvc.Error("設定時區失敗 userID=%s err=%s", userID, err.Error())
+		s.replyT(event, "timezone.failed")
+		return
+	}
+	s.timezones.Set(userID, loc)
+	hour, minute := summaryTime()
+	s.replyT(event, "timezone.changed", arg, fmt.Sprintf("%02d:%02d", hour, minute))
+}
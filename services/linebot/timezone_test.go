@@ -0,0 +1,74 @@
+package linebot
+
+import (
+	"testing"
+	"time"
+
+	"project/models"
+)
+
+func TestLocationOf(t *testing.T) {
+	tests := []struct {
+		timezone string
+		want     string
+	}{
+		{"", "Asia/Taipei"},
+		{"Local", "Asia/Taipei"},
+		{"Not/AZone", "Asia/Taipei"},
+		{"Asia/Tokyo", "Asia/Tokyo"},
+		{"America/New_York", "America/New_York"},
+	}
+	for _, tt := range tests {
+		if got := locationOf(&models.User{Timezone: tt.timezone}).String(); got != tt.want {
+			t.Errorf("locationOf(%q) = %s, want %s", tt.timezone, got, tt.want)
+		}
+	}
+}
+
+func TestDiaryRangeUsesLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	// 台北 2026-03-04 10:00 是紐約 2026-03-03 21:00
+	now := time.Date(2026, 3, 4, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		period   diaryPeriod
+		loc      *time.Location
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{diaryToday, taipeiLocation, time.Date(2026, 3, 4, 0, 0, 0, 0, taipeiLocation), time.Date(2026, 3, 5, 0, 0, 0, 0, taipeiLocation)},
+		{diaryToday, newYork, time.Date(2026, 3, 3, 0, 0, 0, 0, newYork), time.Date(2026, 3, 4, 0, 0, 0, 0, newYork)},
+		{diaryYesterday, newYork, time.Date(2026, 3, 2, 0, 0, 0, 0, newYork), time.Date(2026, 3, 3, 0, 0, 0, 0, newYork)},
+		{diaryThisWeek, newYork, time.Date(2026, 3, 2, 0, 0, 0, 0, newYork), time.Date(2026, 3, 4, 0, 0, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		from, to := tt.period.Range(now, tt.loc)
+		if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+			t.Errorf("%s in %s: [%s, %s), want [%s, %s)", tt.period, tt.loc, from, to, tt.wantFrom, tt.wantTo)
+		}
+	}
+}
+
+func TestMealCodeUsesLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	// UTC 03:30 為台北 11:30（午餐）、東京 12:30（午餐）、UTC 本身 03:30（點心）
+	at := time.Date(2026, 3, 4, 3, 30, 0, 0, time.UTC)
+	tests := []struct {
+		loc  *time.Location
+		want string
+	}{
+		{taipeiLocation, mealLunch},
+		{tokyo, mealLunch},
+		{time.UTC, mealSnack},
+	}
+	for _, tt := range tests {
+		if got := mealCode(at, tt.loc); got != tt.want {
+			t.Errorf("mealCode in %s = %s, want %s", tt.loc, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package linebot
+
+import (
+	"time"
+
+	"project/models"
+	"project/services/imageai"
+	logsvc "project/services/log"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+)
+
+// recordUsage 將一次成功的辨識呼叫寫入 recognition_usages（token 數、模型、耗時與估算費用）；
+// 快取命中時 token 與費用為 0。資料庫未設定時略過，寫入失敗只記 log，不影響回覆。
+func (s *LineBotService) recordUsage(event *linebot.Event, userID, source string, images int, result *imageai.RecognitionResult, latency time.Duration) {
+	if s.db == nil || result == nil {
+		return
+	}
+	usage := &models.RecognitionUsage{
+		LineUserID: userID,
+		GroupID:    chatID(event.Source),
+		Source:     source,
+		Images:     images,
+		LatencyMs:  latency.Milliseconds(),
+		CacheHit:   result.CacheHit,
+	}
+	if u := result.Usage; u != nil {
+		usage.Provider = u.Provider
+		usage.Model = u.Model
+		usage.InputTokens = u.InputTokens
+		usage.OutputTokens = u.OutputTokens
+		usage.CostUSD = u.Cost()
+	}
+	if err := s.db.CreateRecognitionUsage(usage); err != nil {
+		logsvc.Error("寫入辨識用量失敗 userID=%s err=%s", userID, err.Error())
+	}
+}
@@ -263,3 +263,14 @@ func (c *Client) Pipeline(commands ...func(pipe redis.Pipeliner) error) ([]redis
 
 	return pipe.Exec(c.ctx)
 }
+
+// Watch 以 WATCH / MULTI 執行樂觀鎖交易：fn 讀取 keys 後以 tx.TxPipelined 寫入，
+// 期間 keys 被其他 client 修改時整筆交易不會執行並回傳 redis.TxFailedErr（呼叫端可重新讀取後重試）
+func (c *Client) Watch(fn func(ctx context.Context, tx *redis.Tx) error, keys ...string) error {
+	if !c.IsAvailable() {
+		return errors.New("Redis 不可用")
+	}
+	return c.client.Watch(c.ctx, func(tx *redis.Tx) error {
+		return fn(c.ctx, tx)
+	}, keys...)
+}
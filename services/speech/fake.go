@@ -0,0 +1,28 @@
+package speech
+
+import (
+	"context"
+)
+
+const defaultFakeText = "白飯一碗、滷雞腿、燙青菜"
+
+// FakeTranscriber 不呼叫外部服務，固定回傳設定的文字（本機開發與測試用）
+type FakeTranscriber struct {
+	Text string
+}
+
+// NewFakeTranscriber 建立固定回傳 text 的語音轉文字；text 為空時使用預設內容
+func NewFakeTranscriber(text string) *FakeTranscriber {
+	if text == "" {
+		text = defaultFakeText
+	}
+	return &FakeTranscriber{Text: text}
+}
+
+// Transcribe 回傳固定文字
+func (t *FakeTranscriber) Transcribe(ctx context.Context, audio []byte, contentType string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return t.Text, nil
+}
@@ -0,0 +1,47 @@
+package speech
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Transcriber 語音轉文字的供應商介面
+type Transcriber interface {
+	// Transcribe 將音訊轉為文字；contentType 為音訊的 MIME type（LINE 語音訊息為 audio/x-m4a）
+	Transcribe(ctx context.Context, audio []byte, contentType string) (string, error)
+}
+
+type languageKey struct{}
+
+// WithLanguage 指定這次轉文字的語言（ISO-639-1，例如 zh、en、ja），供應商未以設定固定語言時採用
+func WithLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, languageKey{}, language)
+}
+
+// LanguageFrom 取得 ctx 指定的語言，未指定時回傳空字串
+func LanguageFrom(ctx context.Context) string {
+	language, _ := ctx.Value(languageKey{}).(string)
+	return language
+}
+
+// LanguageForLocale 將 i18n 語系（zh-TW、en、ja）轉為 ISO-639-1 語言代碼（zh、en、ja）
+func LanguageForLocale(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return strings.ToLower(language)
+}
+
+// NewTranscriberFromEnv 依環境變數 TRANSCRIBE_PROVIDER 建立語音轉文字供應商：
+//   - whisper（預設）：OpenAI 相容的 /audio/transcriptions 端點
+//   - fake：固定回傳 TRANSCRIBE_FAKE_TEXT，供本機開發與測試使用
+func NewTranscriberFromEnv() (Transcriber, error) {
+	switch provider := strings.ToLower(os.Getenv("TRANSCRIBE_PROVIDER")); provider {
+	case "", "whisper":
+		return NewWhisperTranscriberFromEnv()
+	case "fake":
+		return NewFakeTranscriber(os.Getenv("TRANSCRIBE_FAKE_TEXT")), nil
+	default:
+		return nil, fmt.Errorf("不支援的 TRANSCRIBE_PROVIDER: %s", provider)
+	}
+}
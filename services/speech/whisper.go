@@ -0,0 +1,139 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWhisperBaseURL = "https://api.openai.com/v1"
+	defaultWhisperModel   = "whisper-1"
+	defaultLanguage       = "zh"
+)
+
+// WhisperTranscriber 呼叫 OpenAI 相容的 Whisper 語音轉文字 API（POST {baseURL}/audio/transcriptions）
+type WhisperTranscriber struct {
+	baseURL  string
+	token    string
+	model    string
+	language string // 固定使用的語言；空值時依 ctx 的 WithLanguage（使用者語系），都沒有時為 zh
+	client   *http.Client
+}
+
+// NewWhisperTranscriber 建立 Whisper 語音轉文字（baseURL 例如 https://api.openai.com/v1）；
+// language 不為空時一律使用該語言，否則依每次呼叫的 ctx 決定
+func NewWhisperTranscriber(baseURL, token, model, language string) *WhisperTranscriber {
+	if baseURL == "" {
+		baseURL = defaultWhisperBaseURL
+	}
+	if model == "" {
+		model = defaultWhisperModel
+	}
+	return &WhisperTranscriber{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		token:    token,
+		model:    model,
+		language: language,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// NewWhisperTranscriberFromEnv 從環境變數建立：TRANSCRIBE_BASE_URL、TRANSCRIBE_MODEL、
+// TRANSCRIBE_LANGUAGE（固定語言，未設定時依使用者語系），金鑰使用 TRANSCRIBE_API_KEY，未設定時沿用 OPEN_AI_TOKEN
+func NewWhisperTranscriberFromEnv() (*WhisperTranscriber, error) {
+	token := os.Getenv("TRANSCRIBE_API_KEY")
+	if token == "" {
+		token = os.Getenv("OPEN_AI_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("TRANSCRIBE_API_KEY 或 OPEN_AI_TOKEN 必須設定")
+	}
+	return NewWhisperTranscriber(os.Getenv("TRANSCRIBE_BASE_URL"), token, os.Getenv("TRANSCRIBE_MODEL"), os.Getenv("TRANSCRIBE_LANGUAGE")), nil
+}
+
+// Transcribe 以 multipart/form-data 上傳音訊並取回文字
+func (t *WhisperTranscriber) Transcribe(ctx context.Context, audio []byte, contentType string) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("model", t.model)
+	_ = w.WriteField("response_format", "json")
+	_ = w.WriteField("language", t.languageFor(ctx))
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="audio%s"`, audioExtension(contentType)))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(audio); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("語音轉文字 API 回傳 %d", resp.StatusCode)
+	}
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// languageFor 這次呼叫的語言：固定語言 > ctx 指定的語言 > zh
+func (t *WhisperTranscriber) languageFor(ctx context.Context) string {
+	if t.language != "" {
+		return t.language
+	}
+	if language := LanguageFrom(ctx); language != "" {
+		return language
+	}
+	return defaultLanguage
+}
+
+// audioExtension 依 MIME type 決定上傳檔名的副檔名（Whisper 依副檔名判斷格式）
+func audioExtension(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "mpeg"), strings.Contains(contentType, "mp3"):
+		return ".mp3"
+	case strings.Contains(contentType, "wav"):
+		return ".wav"
+	case strings.Contains(contentType, "ogg"):
+		return ".ogg"
+	case strings.Contains(contentType, "webm"):
+		return ".webm"
+	default:
+		// LINE 語音訊息為 m4a
+		return ".m4a"
+	}
+}
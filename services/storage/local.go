@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logsvc "project/services/log"
+)
+
+const (
+	defaultLocalDir = "storage/uploads"
+	// LocalFilesPath 本機儲存簽章網址的路由前綴（GET {LocalFilesPath}/{key}?expires=&signature=）
+	LocalFilesPath = "/storage/files"
+)
+
+var (
+	// ErrInvalidKey key 為空或含有 ..（避免讀寫儲存目錄以外的檔案）
+	ErrInvalidKey = errors.New("物件 key 無效")
+	// ErrInvalidSignature 簽章網址的簽章不符或已過期
+	ErrInvalidSignature = errors.New("簽章無效或已過期")
+)
+
+var (
+	fallbackSecret     []byte
+	fallbackSecretOnce sync.Once
+)
+
+// LocalStorage 以本機檔案儲存物件（開發環境或單機部署用）；
+// 簽章網址為 {baseURL}{LocalFilesPath}/{key}?expires=<unix 秒>&signature=<HMAC-SHA256>，由 gin 路由驗證後回傳檔案。
+// contentType 與 metadata 存在同目錄的隱藏檔 .{檔名}.meta.json（沒有時依副檔名判斷 contentType）。
+type LocalStorage struct {
+	root    string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocalStorage 建立本機儲存；root 為空時使用 storage/uploads，baseURL 為空時產生相對網址，
+// secret 為空時使用 process 內隨機產生的金鑰（重啟後先前的網址失效）
+func NewLocalStorage(root, baseURL, secret string) (*LocalStorage, error) {
+	if root == "" {
+		root = defaultLocalDir
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("建立本機儲存目錄失敗: %w", err)
+	}
+	key := []byte(secret)
+	if len(key) == 0 {
+		fallbackSecretOnce.Do(func() {
+			fallbackSecret = make([]byte, 32)
+			_, _ = rand.Read(fallbackSecret)
+			logsvc.Warn("STORAGE_LOCAL_SECRET 未設定，改用隨機金鑰（重啟後簽章網址失效）")
+		})
+		key = fallbackSecret
+	}
+	return &LocalStorage{root: root, baseURL: strings.TrimRight(baseURL, "/"), secret: key}, nil
+}
+
+// NewLocalStorageFromEnv 從環境變數建立本機儲存：STORAGE_LOCAL_DIR、STORAGE_LOCAL_BASE_URL、STORAGE_LOCAL_SECRET
+func NewLocalStorageFromEnv() (*LocalStorage, error) {
+	return NewLocalStorage(os.Getenv("STORAGE_LOCAL_DIR"), os.Getenv("STORAGE_LOCAL_BASE_URL"), os.Getenv("STORAGE_LOCAL_SECRET"))
+}
+
+// cleanKey 正規化 key（去掉開頭的 /），拒絕空值與 ..
+func cleanKey(key string) (string, error) {
+	for _, seg := range strings.Split(key, "/") {
+		if seg == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	if key == "" {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
+
+// filePath key 對應的檔案路徑
+func (l *LocalStorage) filePath(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// localMeta 本機檔案的 contentType 與 metadata（存於 metaPath）
+type localMeta struct {
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// metaPath 檔案對應的 metadata 隱藏檔路徑
+func metaPath(name string) string {
+	return filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".meta.json")
+}
+
+// Put 寫入檔案與 metadata
+func (l *LocalStorage) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) error {
+	name, err := l.filePath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(name, body); err != nil {
+		return err
+	}
+	meta, err := json.Marshal(localMeta{ContentType: opts.ContentType, Metadata: opts.Metadata})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(metaPath(name), bytes.NewReader(meta))
+}
+
+// writeFileAtomic 先寫入暫存檔再改名，避免讀到寫到一半的檔案
+func writeFileAtomic(name string, body io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Head 取得檔案資訊與 metadata
+func (l *LocalStorage) Head(ctx context.Context, key string) (*Object, error) {
+	name, err := l.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l.object(key, name, info), nil
+}
+
+// Get 開啟檔案（回傳的 *os.File 可 Seek，供 http.ServeContent 使用）
+func (l *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	name, err := l.filePath(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, nil, ErrNotFound
+	}
+	return f, l.object(key, name, info), nil
+}
+
+// object 由檔案資訊與 metadata 隱藏檔組出 Object
+func (l *LocalStorage) object(key, name string, info fs.FileInfo) *Object {
+	obj := &Object{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		LastModified: info.ModTime(),
+	}
+	if data, err := os.ReadFile(metaPath(name)); err == nil {
+		var meta localMeta
+		if json.Unmarshal(data, &meta) == nil {
+			if meta.ContentType != "" {
+				obj.ContentType = meta.ContentType
+			}
+			obj.Metadata = meta.Metadata
+		}
+	}
+	return obj
+}
+
+// Delete 刪除檔案與 metadata
+func (l *LocalStorage) Delete(ctx context.Context, key string) error {
+	name, err := l.filePath(key)
+	if err != nil {
+		return err
+	}
+	for _, f := range []string{name, metaPath(name)} {
+		if err := os.Remove(f); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List 走訪 prefix 所在目錄，依 key 排序後分頁；cursor 為上一頁最後一筆的 key
+func (l *LocalStorage) List(ctx context.Context, prefix, cursor string, limit int) ([]Object, string, error) {
+	dir := l.root
+	if i := strings.LastIndex(prefix, "/"); i > 0 {
+		sub, err := cleanKey(prefix[:i])
+		if err != nil {
+			return nil, "", err
+		}
+		dir = filepath.Join(l.root, filepath.FromSlash(sub))
+	}
+
+	objects := make([]Object, 0)
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) || (cursor != "" && key <= cursor) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	next := ""
+	if limit > 0 && len(objects) > limit {
+		objects = objects[:limit]
+		next = objects[limit-1].Key
+	}
+	return objects, next, nil
+}
+
+// SignedURL 產生帶 HMAC 簽章、expires 後失效的網址（expires 為 0 時 1 小時有效）
+func (l *LocalStorage) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 {
+		expires = defaultSignedURLExpires
+	}
+	exp := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{"expires": {exp}, "signature": {l.sign(key, exp)}}
+	return l.baseURL + LocalFilesPath + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Verify 驗證簽章網址的 key、expires 與 signature
+func (l *LocalStorage) Verify(key, expires, signature string, now time.Time) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || now.Unix() > exp {
+		return ErrInvalidSignature
+	}
+	if subtle.ConstantTimeCompare([]byte(l.sign(key, expires)), []byte(signature)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// sign HMAC-SHA256(key + "\n" + expires)
+func (l *LocalStorage) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalStorage(t *testing.T, secret string) *LocalStorage {
+	t.Helper()
+	l, err := NewLocalStorage(filepath.Join(t.TempDir(), "uploads"), "", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "food-images/U1/abc.jpg", want: "food-images/U1/abc.jpg"},
+		{key: "/food-images/U1/abc.jpg", want: "food-images/U1/abc.jpg"},
+		{key: "food-images//U1/./abc.jpg", want: "food-images/U1/abc.jpg"},
+		{key: "..", wantErr: true},
+		{key: "../secret.txt", wantErr: true},
+		{key: "food-images/../../etc/passwd", wantErr: true},
+		{key: "food-images/U1/..", wantErr: true},
+		{key: "/../x", wantErr: true},
+		{key: "", wantErr: true},
+		{key: "/", wantErr: true},
+		{key: ".", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := cleanKey(tt.key)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("cleanKey(%q) = %q, %v; want ErrInvalidKey", tt.key, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("cleanKey(%q) = %q, %v; want %q", tt.key, got, err, tt.want)
+		}
+	}
+}
+
+// signedQuery 產生 key 的簽章網址並取出 expires 與 signature
+func signedQuery(t *testing.T, l *LocalStorage, key string, expires time.Duration) (string, string) {
+	t.Helper()
+	raw, err := l.SignedURL(context.Background(), key, expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := LocalFilesPath + "/" + strings.TrimPrefix(key, "/"); u.Path != want {
+		t.Errorf("signed path = %s, want %s", u.Path, want)
+	}
+	return u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestLocalStorageVerify(t *testing.T) {
+	l := newTestLocalStorage(t, "secret")
+	const key = "food-images/U1/abc.jpg"
+	expires, signature := signedQuery(t, l, key, time.Hour)
+	exp, _ := strconv.ParseInt(expires, 10, 64)
+	now := time.Now()
+	forged := strconv.FormatInt(exp+3600, 10)
+
+	tests := []struct {
+		name      string
+		storage   *LocalStorage
+		key       string
+		expires   string
+		signature string
+		now       time.Time
+		wantErr   error
+	}{
+		{name: "valid", key: key, expires: expires, signature: signature, now: now},
+		{name: "leading slash", key: "/" + key, expires: expires, signature: signature, now: now},
+		{name: "at expiry", key: key, expires: expires, signature: signature, now: time.Unix(exp, 0)},
+		{name: "expired", key: key, expires: expires, signature: signature, now: time.Unix(exp+1, 0), wantErr: ErrInvalidSignature},
+		{name: "extended expires", key: key, expires: forged, signature: signature, now: now, wantErr: ErrInvalidSignature},
+		{name: "invalid expires", key: key, expires: "tomorrow", signature: signature, now: now, wantErr: ErrInvalidSignature},
+		{name: "other key", key: "food-images/U2/abc.jpg", expires: expires, signature: signature, now: now, wantErr: ErrInvalidSignature},
+		{name: "tampered signature", key: key, expires: expires, signature: "0" + signature[1:], now: now, wantErr: ErrInvalidSignature},
+		{name: "missing signature", key: key, expires: expires, now: now, wantErr: ErrInvalidSignature},
+		{name: "other secret", storage: newTestLocalStorage(t, "other"), key: key, expires: expires, signature: signature, now: now, wantErr: ErrInvalidSignature},
+		{name: "path traversal", key: "food-images/../../" + key, expires: expires, signature: signature, now: now, wantErr: ErrInvalidKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := l
+			if tt.storage != nil {
+				s = tt.storage
+			}
+			err := s.Verify(tt.key, tt.expires, tt.signature, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	l := newTestLocalStorage(t, "secret")
+	ctx := context.Background()
+	const key = "food-images/U1/abc.jpg"
+	opts := PutOptions{ContentType: "image/jpeg", Metadata: map[string]string{"content-id": "123"}}
+	if err := l.Put(ctx, key, strings.NewReader("jpeg data"), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := l.Head(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Size != int64(len("jpeg data")) || obj.ContentType != "image/jpeg" || obj.Metadata["content-id"] != "123" {
+		t.Errorf("Head = %+v", obj)
+	}
+	body, _, err := l.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "jpeg data" {
+		t.Errorf("Get = %q", data)
+	}
+
+	// metadata 隱藏檔不應出現在列表中
+	objects, next, err := l.List(ctx, "food-images/U1/", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Key != key || next != "" {
+		t.Errorf("List = %+v next=%q", objects, next)
+	}
+
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Head(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Head after Delete = %v, want ErrNotFound", err)
+	}
+	if _, _, err := l.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStorageRejectsTraversal(t *testing.T) {
+	l := newTestLocalStorage(t, "secret")
+	ctx := context.Background()
+	outside := filepath.Join(filepath.Dir(l.root), "outside.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	const key = "../outside.txt"
+	if err := l.Put(ctx, key, strings.NewReader("x"), PutOptions{}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put = %v, want ErrInvalidKey", err)
+	}
+	if _, _, err := l.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Get = %v, want ErrInvalidKey", err)
+	}
+	if _, err := l.Head(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Head = %v, want ErrInvalidKey", err)
+	}
+	if err := l.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Delete = %v, want ErrInvalidKey", err)
+	}
+	if _, err := l.SignedURL(ctx, key, time.Hour); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("SignedURL = %v, want ErrInvalidKey", err)
+	}
+	if data, err := os.ReadFile(outside); err != nil || string(data) != "secret" {
+		t.Errorf("file outside the root changed: %q %v", data, err)
+	}
+}
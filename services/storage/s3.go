@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Storage 以 AWS S3 儲存物件
+type S3Storage struct {
+	client *awss3.Client
+	bucket string
+}
+
+// NewS3StorageFromEnv 從環境變數建立 S3 儲存：AWS_S3_BUCKET_NAME、AWS_S3_REGION、AWS_S3_ACCESS_KEY_ID、AWS_S3_SECRET_ACCESS_KEY
+func NewS3StorageFromEnv() (*S3Storage, error) {
+	bucket := os.Getenv("AWS_S3_BUCKET_NAME")
+	region := os.Getenv("AWS_S3_REGION")
+	accessKey := os.Getenv("AWS_S3_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_S3_SECRET_ACCESS_KEY")
+
+	if bucket == "" || region == "" || accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("AWS_S3_BUCKET_NAME、AWS_S3_REGION、AWS_S3_ACCESS_KEY_ID、AWS_S3_SECRET_ACCESS_KEY 必須設定")
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion(region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			accessKey,
+			secretKey,
+			"",
+		)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client := awss3.NewFromConfig(cfg, func(o *awss3.Options) {
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+	})
+	return &S3Storage{client: client, bucket: bucket}, nil
+}
+
+// Put 上傳物件至 S3（先讀入記憶體以帶上 ContentLength），Metadata 存為 x-amz-meta-*
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, opts PutOptions) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, &awss3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentType:   aws.String(opts.ContentType),
+		ContentLength: aws.Int64(int64(len(data))),
+		Metadata:      opts.Metadata,
+	})
+	return err
+}
+
+// Head 以 HeadObject 取得物件資訊
+func (s *S3Storage) Head(ctx context.Context, key string) (*Object, error) {
+	out, err := s.client.HeadObject(ctx, &awss3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &Object{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		LastModified: aws.ToTime(out.LastModified),
+		Metadata:     out.Metadata,
+	}, nil
+}
+
+// Get 讀取 S3 物件
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	out, err := s.client.GetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	return out.Body, &Object{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		LastModified: aws.ToTime(out.LastModified),
+		Metadata:     out.Metadata,
+	}, nil
+}
+
+// Delete 刪除 S3 物件
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &awss3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// List 以 ListObjectsV2 列出物件，cursor 對應 StartAfter（從該 key 之後開始列）
+func (s *S3Storage) List(ctx context.Context, prefix, cursor string, limit int) ([]Object, string, error) {
+	input := &awss3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+	if limit > 0 {
+		input.MaxKeys = aws.Int32(int32(limit))
+	}
+	if cursor != "" {
+		input.StartAfter = aws.String(cursor)
+	}
+	out, err := s.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, "", err
+	}
+	objects := make([]Object, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		objects = append(objects, Object{
+			Key:          aws.ToString(obj.Key),
+			Size:         aws.ToInt64(obj.Size),
+			LastModified: aws.ToTime(obj.LastModified),
+		})
+	}
+	next := ""
+	if aws.ToBool(out.IsTruncated) && len(objects) > 0 {
+		next = objects[len(objects)-1].Key
+	}
+	return objects, next, nil
+}
+
+// SignedURL 產生 S3 Presigned GET URL（expires 為 0 時 1 小時有效）
+func (s *S3Storage) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if expires <= 0 {
+		expires = defaultSignedURLExpires
+	}
+	presignClient := awss3.NewPresignClient(s.client)
+	presigned, err := presignClient.PresignGetObject(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, func(opts *awss3.PresignOptions) {
+		opts.Expires = expires
+	})
+	if err != nil {
+		return "", err
+	}
+	return presigned.URL, nil
+}
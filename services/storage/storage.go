@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 物件不存在
+var ErrNotFound = errors.New("物件不存在")
+
+// 物件 metadata 的欄位名稱（S3 為 x-amz-meta-*，key 一律小寫）
+const (
+	MetaLineMessageID = "line-message-id" // 原始 LINE 訊息 ID
+	MetaUploadedAt    = "uploaded-at"     // 上傳時間（RFC 3339）
+)
+
+// Object 儲存空間中物件的資訊（List 不含 ContentType 與 Metadata）
+type Object struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	ContentType  string            `json:"content_type,omitempty"`
+	LastModified time.Time         `json:"last_modified"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// PutOptions 寫入物件的選項
+type PutOptions struct {
+	ContentType string
+	Metadata    map[string]string
+}
+
+// Storage 圖片等檔案的儲存後端介面
+type Storage interface {
+	// Put 寫入物件（同 key 已存在時覆寫）
+	Put(ctx context.Context, key string, body io.Reader, opts PutOptions) error
+	// Head 取得物件資訊（含 Metadata）但不讀取內容；不存在時回傳 ErrNotFound
+	Head(ctx context.Context, key string) (*Object, error)
+	// Get 讀取物件，呼叫端需關閉回傳的 ReadCloser；不存在時回傳 ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// Delete 刪除物件（不存在時不視為錯誤）
+	Delete(ctx context.Context, key string) error
+	// List 依 key 排序列出 prefix 底下的物件，每次最多 limit 筆；
+	// cursor 為 key，只列出排在 cursor 之後的物件（第一頁傳空字串，下一頁傳 next 或已處理的最後一個 key），
+	// next 為本頁最後一筆的 key，空字串表示沒有下一頁
+	List(ctx context.Context, prefix, cursor string, limit int) (objects []Object, next string, err error)
+	// SignedURL 產生在 expires 內有效、不需其他驗證即可讀取物件的網址
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// defaultSignedURLExpires SignedURL 未指定有效時間時的預設值
+const defaultSignedURLExpires = time.Hour
+
+// NewFromEnv 依環境變數 STORAGE_BACKEND 建立儲存後端：
+//   - s3：AWS S3（需設定 AWS_S3_* 四個變數）
+//   - local：本機檔案（STORAGE_LOCAL_*），簽章網址由 /storage/files 路由提供
+//   - 未設定：有設定 AWS_S3_BUCKET_NAME 時使用 s3，否則使用 local
+func NewFromEnv() (Storage, error) {
+	backend := strings.ToLower(os.Getenv("STORAGE_BACKEND"))
+	if backend == "" {
+		backend = "local"
+		if os.Getenv("AWS_S3_BUCKET_NAME") != "" {
+			backend = "s3"
+		}
+	}
+	switch backend {
+	case "s3":
+		return NewS3StorageFromEnv()
+	case "local":
+		return NewLocalStorageFromEnv()
+	default:
+		return nil, fmt.Errorf("不支援的 STORAGE_BACKEND: %s", backend)
+	}
+}
+
+// imageExtensions 圖片 MIME type 對應的副檔名
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/heic": ".heic",
+	"image/heif": ".heif",
+}
+
+// DetectImageType 依內容判斷圖片的 MIME type；無法判斷時（例如 HEIC）使用 fallback（通常為 LINE 回傳的 Content-Type），
+// 兩者皆無法對應圖片格式時回傳 application/octet-stream
+func DetectImageType(data []byte, fallback string) string {
+	detected := http.DetectContentType(data)
+	if _, ok := imageExtensions[detected]; ok {
+		return detected
+	}
+	if mediaType, _, err := mime.ParseMediaType(fallback); err == nil {
+		if _, ok := imageExtensions[mediaType]; ok {
+			return mediaType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// ImageKey 使用者上傳圖片的物件 key：food-images/{userID}/{內容的 SHA-256}{副檔名}。
+// 相同內容一定得到相同 key（可用 Head 判斷是否已上傳），不同內容不會互相覆寫。
+func ImageKey(userID string, data []byte, contentType string) string {
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		ext = ".bin"
+	}
+	sum := sha256.Sum256(data)
+	return ImagePrefix(userID) + hex.EncodeToString(sum[:]) + ext
+}
+
+// ImagePrefix 使用者圖片（含各版本）所在的 key 前綴：food-images/{userID}/
+func ImagePrefix(userID string) string {
+	if userID == "" {
+		userID = "unknown"
+	}
+	return "food-images/" + userID + "/"
+}
+
+// 圖片的各尺寸版本（縮圖與顯示用版本由原圖縮放而來，皆為 JPEG）
+const (
+	VariantOriginal = "original" // 原圖
+	VariantThumb    = "thumb"    // 列表用縮圖
+	VariantDisplay  = "display"  // 單張顯示用
+)
+
+// ValidVariant 是否為支援的圖片版本（空字串視為原圖）
+func ValidVariant(variant string) bool {
+	switch variant {
+	case "", VariantOriginal, VariantThumb, VariantDisplay:
+		return true
+	}
+	return false
+}
+
+// VariantKey 圖片版本的物件 key，與原圖放在同一目錄：
+// food-images/{userID}/{SHA-256}.png 的縮圖為 food-images/{userID}/{SHA-256}_thumb.jpg；原圖（或空字串）回傳 key 本身
+func VariantKey(key, variant string) string {
+	if variant == "" || variant == VariantOriginal {
+		return key
+	}
+	return strings.TrimSuffix(key, path.Ext(key)) + "_" + variant + ".jpg"
+}
+
+// IsVariantKey key 是否為縮圖或顯示用版本（而非原圖）
+func IsVariantKey(key string) bool {
+	for _, variant := range []string{VariantThumb, VariantDisplay} {
+		if strings.HasSuffix(key, "_"+variant+".jpg") {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteImage 刪除原圖與其縮圖、顯示用版本（不存在的版本不視為錯誤）
+func DeleteImage(ctx context.Context, st Storage, key string) error {
+	for _, variant := range []string{VariantOriginal, VariantThumb, VariantDisplay} {
+		if err := st.Delete(ctx, VariantKey(key, variant)); err != nil {
+			return fmt.Errorf("刪除 %s 失敗: %w", VariantKey(key, variant), err)
+		}
+	}
+	return nil
+}